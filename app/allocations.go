@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Sales channels stock can be allocated to
+const (
+	ChannelWeb         = "web"
+	ChannelMarketplace = "marketplace"
+	ChannelB2B         = "b2b"
+)
+
+// isKnownChannel reports whether channel is one of the supported sales channels
+func isKnownChannel(channel string) bool {
+	switch channel {
+	case ChannelWeb, ChannelMarketplace, ChannelB2B:
+		return true
+	default:
+		return false
+	}
+}
+
+// AllocationRule ring-fences part of a warehouse's stock for one channel,
+// either a percentage or a fixed number of units. An empty product ID
+// applies to every product at the warehouse without rules of its own
+type AllocationRule struct {
+	Warehouse string `json:"warehouse"`
+	ProductID string `json:"product_id,omitempty"`
+	Channel   string `json:"channel"`
+	Percent   int    `json:"percent,omitempty"`
+	Units     int    `json:"units,omitempty"`
+}
+
+// AllocationPlan holds channel allocation rules, loaded from a JSON file
+type AllocationPlan struct {
+	filePath string
+	rules    map[stockKey][]AllocationRule
+}
+
+// NewAllocationPlan creates a plan backed by the given JSON file
+func NewAllocationPlan(filePath string) *AllocationPlan {
+	return &AllocationPlan{
+		filePath: filePath,
+		rules:    map[stockKey][]AllocationRule{},
+	}
+}
+
+// Load reads and validates the allocation rules
+func (p *AllocationPlan) Load() error {
+	data, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read allocation plan: %w", err)
+	}
+
+	var rules []AllocationRule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return fmt.Errorf("failed to parse allocation plan JSON: %w", err)
+	}
+
+	return p.setRules(rules)
+}
+
+// setRules validates rules and replaces the plan with them
+func (p *AllocationPlan) setRules(rules []AllocationRule) error {
+	byKey := map[stockKey][]AllocationRule{}
+	percents := map[stockKey]int{}
+	for _, rule := range rules {
+		if rule.Warehouse == "" {
+			return fmt.Errorf("allocation rule without warehouse in %s", p.filePath)
+		}
+		if !isKnownChannel(rule.Channel) {
+			return fmt.Errorf("allocation rule for %s: unknown channel %q", rule.Warehouse, rule.Channel)
+		}
+		if (rule.Percent > 0) == (rule.Units > 0) || rule.Percent < 0 || rule.Units < 0 {
+			return fmt.Errorf("allocation rule for %s at %s: set either a positive percent or positive units", rule.Channel, rule.Warehouse)
+		}
+
+		key := stockKey{rule.ProductID, rule.Warehouse}
+		for _, existing := range byKey[key] {
+			if existing.Channel == rule.Channel {
+				return fmt.Errorf("duplicate allocation rule for %s at %s (product %q)", rule.Channel, rule.Warehouse, rule.ProductID)
+			}
+		}
+		percents[key] += rule.Percent
+		if percents[key] > 100 {
+			return fmt.Errorf("allocations at %s (product %q) exceed 100%%", rule.Warehouse, rule.ProductID)
+		}
+		byKey[key] = append(byKey[key], rule)
+	}
+
+	// Percentages are taken from the whole stock before fixed units are carved out
+	for _, rules := range byKey {
+		sort.SliceStable(rules, func(i, j int) bool { return rules[i].Percent > 0 && rules[j].Percent == 0 })
+	}
+
+	p.rules = byKey
+	return nil
+}
+
+// Allocate splits stock between the channels allocated at the warehouse and
+// returns the channel's own allocation and the unallocated shared pool.
+// Product rules replace the warehouse-wide rules. Fixed units are capped at
+// the stock left once earlier rules are served. ok is false if no rules apply
+func (p *AllocationPlan) Allocate(productID, warehouse, channel string, stock int) (allocated, shared int, ok bool) {
+	rules, ok := p.rules[stockKey{productID, warehouse}]
+	if !ok {
+		rules, ok = p.rules[stockKey{"", warehouse}]
+	}
+	if !ok {
+		return 0, stock, false
+	}
+
+	shared = stock
+	for _, rule := range rules {
+		share := rule.Units
+		if rule.Percent > 0 {
+			share = stock * rule.Percent / 100
+		}
+		share = min(share, shared)
+		shared -= share
+		if rule.Channel == channel {
+			allocated = share
+		}
+	}
+	return allocated, shared, true
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newAllocationService returns a service over the mock adapter where DE-Berlin
+// allocates 50% to web, 20% to marketplace and 10 units to b2b, and PROD-456
+// has only a 10 unit b2b allocation there
+func newAllocationService(t *testing.T) *AvailabilityService {
+	t.Helper()
+	allocations := NewAllocationPlan("allocations.json")
+	err := allocations.setRules([]AllocationRule{
+		{Warehouse: "DE-Berlin", Channel: ChannelB2B, Units: 10},
+		{Warehouse: "DE-Berlin", Channel: ChannelWeb, Percent: 50},
+		{Warehouse: "DE-Berlin", Channel: ChannelMarketplace, Percent: 20},
+		{Warehouse: "DE-Berlin", ProductID: "PROD-456", Channel: ChannelB2B, Units: 10},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set allocation rules: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithAllocationPlan(allocations))
+}
+
+func TestCheckAvailability_ChannelAllocationPlusSharedPool(t *testing.T) {
+	service := newAllocationService(t)
+
+	// PROD-123 has 100 units: web 50, marketplace 20, b2b 10, shared 20.
+	// Web draws on 70, 63 after reserve
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 63, WarehouseLocation: "DE-Berlin", Channel: ChannelWeb})
+	if !resp.Available || resp.AvailableQuantity != 63 {
+		t.Errorf("Expected 63 available to web, got %+v", resp)
+	}
+	if a := resp.ChannelAllocation; a == nil || a.AllocatedQuantity != 50 || a.SharedQuantity != 20 {
+		t.Errorf("Expected web allocation 50 plus shared 20, got %+v", a)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 64, WarehouseLocation: "DE-Berlin", Channel: ChannelWeb})
+	if resp.Available {
+		t.Error("Expected other channels' allocations to be ring-fenced")
+	}
+
+	// b2b draws on its 10 units plus the shared 20, 27 after reserve
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 28, WarehouseLocation: "DE-Berlin", Channel: ChannelB2B})
+	if resp.Available || resp.AvailableQuantity != 27 {
+		t.Errorf("Expected 27 available to b2b, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_NoChannelUsesAllStock(t *testing.T) {
+	service := newAllocationService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 90, WarehouseLocation: "DE-Berlin"})
+	if !resp.Available || resp.AvailableQuantity != 90 || resp.ChannelAllocation != nil {
+		t.Errorf("Expected all 90 units available without a channel, got %+v", resp)
+	}
+}
+
+func TestHandleCheckAvailability_NoChannelMatchesBaseline(t *testing.T) {
+	check := func(options ...ServiceOption) Response {
+		dataset, err := LoadDataset(".", DefaultConfig().Inventory, options...)
+		if err != nil {
+			t.Fatalf("Failed to load dataset: %v", err)
+		}
+		defer dataset.Close()
+		handler := NewAvailabilityHandler(dataset.Service, dataset.Warehouses)
+		req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(`{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "DE-Berlin"}`))
+		rec := httptest.NewRecorder()
+		handler.HandleCheckAvailability(rec, req)
+		var resp Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+		return resp
+	}
+
+	// The shipped allocations ring-fence DE-Berlin stock for every channel
+	got, want := check(), check(WithAllocationPlan(nil))
+	if got.AvailableQuantity != want.AvailableQuantity || got.ChannelAllocation != nil {
+		t.Errorf("Expected available quantity %d without a channel, got %d with %+v", want.AvailableQuantity, got.AvailableQuantity, got.ChannelAllocation)
+	}
+}
+
+func TestCheckAvailability_ProductAllocationOverridesWarehouse(t *testing.T) {
+	service := newAllocationService(t)
+
+	// PROD-456 has 25 units; only b2b's 10 are ring-fenced, so web shares 15
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-456", Quantity: 1, WarehouseLocation: "DE-Berlin", Channel: ChannelWeb})
+	if a := resp.ChannelAllocation; a == nil || a.AllocatedQuantity != 0 || a.SharedQuantity != 15 {
+		t.Errorf("Expected web to share 15 units, got %+v", a)
+	}
+}
+
+func TestCheckAvailability_NoAllocationRulesUseAllStock(t *testing.T) {
+	service := newAllocationService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 45, WarehouseLocation: "US-NewYork", Channel: ChannelWeb})
+	if !resp.Available || resp.ChannelAllocation != nil {
+		t.Errorf("Expected all stock available without allocation rules, got %+v", resp)
+	}
+}
+
+func TestAllocationPlan_FixedUnitsCappedAtStock(t *testing.T) {
+	plan := NewAllocationPlan("allocations.json")
+	plan.setRules([]AllocationRule{
+		{Warehouse: "DE-Berlin", Channel: ChannelWeb, Percent: 80},
+		{Warehouse: "DE-Berlin", Channel: ChannelB2B, Units: 50},
+	})
+
+	allocated, shared, ok := plan.Allocate("PROD-1", "DE-Berlin", ChannelB2B, 100)
+	if !ok || allocated != 20 || shared != 0 {
+		t.Errorf("Expected b2b capped at the 20 units left, got allocated=%d shared=%d", allocated, shared)
+	}
+}
+
+func TestAllocationPlan_RejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []AllocationRule
+	}{
+		{"over 100 percent", []AllocationRule{
+			{Warehouse: "DE-Berlin", Channel: ChannelWeb, Percent: 60},
+			{Warehouse: "DE-Berlin", Channel: ChannelMarketplace, Percent: 50},
+		}},
+		{"percent and units", []AllocationRule{{Warehouse: "DE-Berlin", Channel: ChannelWeb, Percent: 10, Units: 5}}},
+		{"unknown channel", []AllocationRule{{Warehouse: "DE-Berlin", Channel: "retail", Percent: 10}}},
+		{"duplicate channel", []AllocationRule{
+			{Warehouse: "DE-Berlin", Channel: ChannelWeb, Percent: 10},
+			{Warehouse: "DE-Berlin", Channel: ChannelWeb, Units: 5},
+		}},
+	}
+
+	for _, tt := range tests {
+		if err := NewAllocationPlan("allocations.json").setRules(tt.rules); err == nil {
+			t.Errorf("%s: expected rules to be rejected", tt.name)
+		}
+	}
+}
+
+func TestHandleCheckAvailability_RejectsUnknownChannel(t *testing.T) {
+	rec, _ := postAvailability(t, NewMockInventoryAdapter(), `{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "DE-Berlin", "channel": "retail"}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown channel, got %d", rec.Code)
+	}
+}
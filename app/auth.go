@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes granted to API keys and tokens. Admin grants every scope
+const (
+	ScopeAvailabilityRead = "availability:read" // availability checks and warehouse lookups
+	ScopeInventoryWrite   = "inventory:write"   // endpoints that change inventory
+	ScopeAdmin            = "admin"             // operational endpoints such as /metrics
+)
+
+// knownScopes lists the scopes credentials may be granted
+var knownScopes = []string{ScopeAvailabilityRead, ScopeInventoryWrite, ScopeAdmin}
+
+// Authentication errors. Authenticators return ErrNoCredentials when a
+// request carries no credential of their kind, so the next one can try
+var (
+	ErrNoCredentials      = errors.New("no credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string   // API key name or token subject
+	Scopes  []string // granted scopes
+	Tenant  string   // tenant the credential is limited to; "" for any
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAdmin)
+}
+
+// Authenticator verifies one kind of credential carried by a request
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Auth is the authentication middleware. Routes opt in with Require; a nil
+// *Auth leaves every route open
+type Auth struct {
+	authenticators []Authenticator
+}
+
+// NewAuth creates middleware trying each authenticator in turn
+func NewAuth(authenticators ...Authenticator) *Auth {
+	return &Auth{authenticators: authenticators}
+}
+
+// NewAuthFromConfig builds the middleware for the configured API keys file
+// and JWKS file, both relative to the data directory. It returns nil when
+// authentication is disabled
+func NewAuthFromConfig(cfg AuthConfig, dataDir string) (*Auth, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dataDir, path)
+	}
+
+	var authenticators []Authenticator
+	if cfg.APIKeysFile != "" {
+		keys, err := LoadAPIKeys(resolve(cfg.APIKeysFile))
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, keys)
+	}
+	if cfg.JWKSFile != "" {
+		jwt, err := NewJWTAuthenticator(resolve(cfg.JWKSFile), cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, jwt)
+	}
+	return NewAuth(authenticators...), nil
+}
+
+// principalKey is the context key for the authenticated principal
+type principalKey struct{}
+
+// principalFromContext returns the principal authenticated for a request, or nil
+func principalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Require lets requests through to next only if they authenticate with a
+// credential granted scope. Missing or invalid credentials are answered
+// with 401, a credential without the scope with 403
+func (a *Auth) Require(scope string, next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r)
+		if err != nil {
+			challenge := `Bearer realm="availability"`
+			if errors.Is(err, ErrInvalidCredentials) {
+				challenge += `, error="invalid_token"`
+			}
+			w.Header().Set("WWW-Authenticate", challenge)
+			slog.InfoContext(r.Context(), "authentication failed", "error", err)
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		addLogAttrs(r.Context(), slog.String("subject", p.Subject))
+		spanFromContext(r.Context()).SetAttributes(slog.String("auth.subject", p.Subject))
+		if !p.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="availability", error="insufficient_scope", scope=%q`, scope))
+			http.Error(w, "credentials do not grant "+scope, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, p)))
+	})
+}
+
+// authenticate returns the principal of the first authenticator that
+// recognizes the request's credential
+func (a *Auth) authenticate(r *http.Request) (*Principal, error) {
+	for _, authenticator := range a.authenticators {
+		p, err := authenticator.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header, or ""
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// isJWT reports whether a token has the three dot-separated parts of a JWT
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// validateScopes rejects scopes the service does not know
+func validateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !slices.Contains(knownScopes, scope) {
+			return fmt.Errorf("unknown scope %q (want one of %s)", scope, strings.Join(knownScopes, ", "))
+		}
+	}
+	return nil
+}
+
+// APIKey is a static API key as configured. Only the key's SHA-256 hash is
+// stored, so the file never holds usable keys
+type APIKey struct {
+	Name   string   `json:"name"`
+	SHA256 string   `json:"sha256"` // hex SHA-256 of the key
+	Scopes []string `json:"scopes"`
+	Tenant string   `json:"tenant,omitempty"` // limits the key to one tenant
+}
+
+// apiKeyHash is the SHA-256 of an API key, the only form in which keys are
+// configured and looked up
+type apiKeyHash [sha256.Size]byte
+
+// parseAPIKeyHash decodes a configured hex SHA-256 of an API key
+func parseAPIKeyHash(s string) (apiKeyHash, error) {
+	hash, err := hex.DecodeString(s)
+	if err != nil || len(hash) != sha256.Size {
+		return apiKeyHash{}, fmt.Errorf("sha256 must be 64 hex characters")
+	}
+	return apiKeyHash(hash), nil
+}
+
+// APIKeyAuthenticator accepts static API keys sent as bearer tokens
+type APIKeyAuthenticator struct {
+	keys map[apiKeyHash]*Principal
+}
+
+// LoadAPIKeys reads API keys from a JSON file
+func LoadAPIKeys(path string) (*APIKeyAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys file: %w", err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys file %s: %w", path, err)
+	}
+	a, err := NewAPIKeyAuthenticator(keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// NewAPIKeyAuthenticator creates an authenticator for the given keys
+func NewAPIKeyAuthenticator(keys []APIKey) (*APIKeyAuthenticator, error) {
+	a := &APIKeyAuthenticator{keys: map[apiKeyHash]*Principal{}}
+	for _, key := range keys {
+		if key.Name == "" {
+			return nil, fmt.Errorf("API key without a name")
+		}
+		hash, err := parseAPIKeyHash(key.SHA256)
+		if err != nil {
+			return nil, fmt.Errorf("API key %s: %w", key.Name, err)
+		}
+		if err := validateScopes(key.Scopes); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", key.Name, err)
+		}
+		a.keys[hash] = &Principal{Subject: key.Name, Scopes: key.Scopes, Tenant: key.Tenant}
+	}
+	return a, nil
+}
+
+// Authenticate looks up the hash of a bearer token that is not a JWT
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" || isJWT(token) {
+		return nil, ErrNoCredentials
+	}
+	p, ok := a.keys[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown API key", ErrInvalidCredentials)
+	}
+	return p, nil
+}
+
+// jwtLeeway allows for clock skew when checking token lifetimes
+const jwtLeeway = 30 * time.Second
+
+// JWTAuthenticator accepts RS256-signed JWT bearer tokens, verified against
+// the public keys of a local JWKS file. Scopes come from the space-separated
+// "scope" claim and the tenant from the "tenant" claim
+type JWTAuthenticator struct {
+	keys     map[string]*rsa.PublicKey // by key ID
+	issuer   string                    // required "iss" when set
+	audience string                    // required in "aud" when set
+}
+
+// NewJWTAuthenticator loads the RSA keys of a JWKS file
+func NewJWTAuthenticator(jwksFile, issuer, audience string) (*JWTAuthenticator, error) {
+	data, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file %s: %w", jwksFile, err)
+	}
+
+	a := &JWTAuthenticator{keys: map[string]*rsa.PublicKey{}, issuer: issuer, audience: audience}
+	for _, key := range jwks.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(key.N)
+		e, errE := base64.RawURLEncoding.DecodeString(key.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("JWKS file %s: invalid RSA key %q", jwksFile, key.Kid)
+		}
+		a.keys[key.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(a.keys) == 0 {
+		return nil, fmt.Errorf("JWKS file %s has no RSA signing keys", jwksFile)
+	}
+	return a, nil
+}
+
+// Authenticate verifies a JWT bearer token's signature and claims
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := bearerToken(r)
+	if token == "" || !isJWT(token) {
+		return nil, ErrNoCredentials
+	}
+	p, err := a.verify(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return p, nil
+}
+
+// verify checks the token and returns the principal its claims describe
+func (a *JWTAuthenticator) verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	// Only RS256 is accepted, so a token cannot pick a weaker algorithm
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	key, ok := a.keys[header.Kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID %q", header.Kid)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("invalid signature")
+	}
+
+	var claims struct {
+		Subject   string   `json:"sub"`
+		Issuer    string   `json:"iss"`
+		Audience  audience `json:"aud"`
+		ExpiresAt *int64   `json:"exp"`
+		NotBefore *int64   `json:"nbf"`
+		Scope     string   `json:"scope"`
+		Tenant    string   `json:"tenant"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	current := now()
+	switch {
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("token has no expiry")
+	case current.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)):
+		return nil, fmt.Errorf("token expired")
+	case claims.NotBefore != nil && current.Before(time.Unix(*claims.NotBefore, 0).Add(-jwtLeeway)):
+		return nil, fmt.Errorf("token not yet valid")
+	case a.issuer != "" && claims.Issuer != a.issuer:
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	case a.audience != "" && !slices.Contains(claims.Audience, a.audience):
+		return nil, fmt.Errorf("token not issued for audience %q", a.audience)
+	}
+	return &Principal{Subject: claims.Subject, Scopes: strings.Fields(claims.Scope), Tenant: claims.Tenant}, nil
+}
+
+// decodeJWTPart decodes a base64url JSON part of a JWT
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// audience is the JWT "aud" claim, which may be a string or a list
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
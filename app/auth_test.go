@@ -0,0 +1,227 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRSAKey is the signing key for test tokens, generated once
+var testRSAKey = sync.OnceValue(func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+})
+
+// hashAPIKey returns the hex SHA-256 of a key, as the API keys file holds it
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// writeJWKS writes a JWKS file holding the test key's public half under kid
+func writeJWKS(t *testing.T, dir, kid string) string {
+	t.Helper()
+	public := testRSAKey().PublicKey
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+	}}}
+	data, _ := json.Marshal(jwks)
+	path := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("Failed to write JWKS: %v", err)
+	}
+	return path
+}
+
+// signJWT returns a token with the given header and claims, signed RS256 with the test key
+func signJWT(header, claims map[string]any) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, testRSAKey(), crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// serveWithAuth sends a GET with the given Authorization header through a
+// route requiring scope and returns the response
+func serveWithAuth(auth *Auth, scope, authorization string) *httptest.ResponseRecorder {
+	handler := auth.Require(scope, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(principalFromContext(r.Context()).Subject))
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth_APIKeys(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]APIKey{
+		{Name: "checkout", SHA256: hashAPIKey("checkout-secret"), Scopes: []string{ScopeAvailabilityRead}},
+		{Name: "ops", SHA256: hashAPIKey("ops-secret"), Scopes: []string{ScopeAdmin}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	auth := NewAuth(keys)
+
+	tests := []struct {
+		name          string
+		scope         string
+		authorization string
+		wantCode      int
+		wantSubject   string
+	}{
+		{"valid key", ScopeAvailabilityRead, "Bearer checkout-secret", http.StatusOK, "checkout"},
+		{"scheme is case-insensitive", ScopeAvailabilityRead, "bearer checkout-secret", http.StatusOK, "checkout"},
+		{"admin grants every scope", ScopeAvailabilityRead, "Bearer ops-secret", http.StatusOK, "ops"},
+		{"missing credentials", ScopeAvailabilityRead, "", http.StatusUnauthorized, ""},
+		{"unknown key", ScopeAvailabilityRead, "Bearer guessed", http.StatusUnauthorized, ""},
+		{"other scheme", ScopeAvailabilityRead, "Basic Y2hlY2tvdXQ6c2VjcmV0", http.StatusUnauthorized, ""},
+		{"key without the scope", ScopeAdmin, "Bearer checkout-secret", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithAuth(auth, tt.scope, tt.authorization)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != tt.wantSubject {
+				t.Errorf("Expected subject %q, got %q", tt.wantSubject, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected a Bearer challenge, got %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestNewAPIKeyAuthenticator_RejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		key  APIKey
+	}{
+		{"missing name", APIKey{SHA256: hashAPIKey("k"), Scopes: []string{ScopeAdmin}}},
+		{"plain key instead of hash", APIKey{Name: "k", SHA256: "my-secret-key", Scopes: []string{ScopeAdmin}}},
+		{"unknown scope", APIKey{Name: "k", SHA256: hashAPIKey("k"), Scopes: []string{"inventory:delete"}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewAPIKeyAuthenticator([]APIKey{tt.key}); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestAuth_JWT(t *testing.T) {
+	jwt, err := NewJWTAuthenticator(writeJWKS(t, t.TempDir(), "key-1"), "https://auth.example.com", "availability")
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	auth := NewAuth(jwt)
+
+	header := map[string]any{"alg": "RS256", "kid": "key-1", "typ": "JWT"}
+	claims := func(changes map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "checkout-service",
+			"iss":   "https://auth.example.com",
+			"aud":   []string{"availability", "orders"},
+			"exp":   now().Add(time.Hour).Unix(),
+			"scope": "availability:read",
+		}
+		for key, value := range changes {
+			if value == nil {
+				delete(c, key)
+			} else {
+				c[key] = value
+			}
+		}
+		return c
+	}
+	valid := signJWT(header, claims(nil))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name     string
+		scope    string
+		token    string
+		wantCode int
+	}{
+		{"valid token", ScopeAvailabilityRead, valid, http.StatusOK},
+		{"audience as a string", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"aud": "availability"})), http.StatusOK},
+		{"within clock leeway", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"exp": now().Add(-10 * time.Second).Unix()})), http.StatusOK},
+		{"expired", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"exp": now().Add(-time.Hour).Unix()})), http.StatusUnauthorized},
+		{"no expiry", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"exp": nil})), http.StatusUnauthorized},
+		{"not yet valid", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"nbf": now().Add(time.Hour).Unix()})), http.StatusUnauthorized},
+		{"other issuer", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"iss": "https://evil.example.com"})), http.StatusUnauthorized},
+		{"other audience", ScopeAvailabilityRead, signJWT(header, claims(map[string]any{"aud": "billing"})), http.StatusUnauthorized},
+		{"unknown key ID", ScopeAvailabilityRead, signJWT(map[string]any{"alg": "RS256", "kid": "key-2"}, claims(nil)), http.StatusUnauthorized},
+		{"other algorithm", ScopeAvailabilityRead, signJWT(map[string]any{"alg": "HS256", "kid": "key-1"}, claims(nil)), http.StatusUnauthorized},
+		{"unsigned", ScopeAvailabilityRead, parts[0] + "." + parts[1] + ".", http.StatusUnauthorized},
+		{"tampered claims", ScopeAvailabilityRead, parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x","exp":9999999999,"scope":"admin"}`)) + "." + parts[2], http.StatusUnauthorized},
+		{"missing scope", ScopeInventoryWrite, valid, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveWithAuth(auth, tt.scope, "Bearer "+tt.token)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, rec.Code, rec.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && rec.Body.String() != "checkout-service" {
+				t.Errorf("Expected the token subject, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewAuthFromConfig(t *testing.T) {
+	dataDir := t.TempDir()
+	writeJWKS(t, dataDir, "key-1")
+	os.WriteFile(filepath.Join(dataDir, "api_keys.json"), []byte(`[
+		{"name": "checkout", "sha256": "`+hashAPIKey("checkout-secret")+`", "scopes": ["availability:read"]}
+	]`), 0o644)
+
+	if auth, err := NewAuthFromConfig(AuthConfig{JWKSFile: "jwks.json"}, dataDir); auth != nil || err != nil {
+		t.Errorf("Expected no middleware while disabled, got %v, %v", auth, err)
+	}
+
+	auth, err := NewAuthFromConfig(AuthConfig{Enabled: true, APIKeysFile: "api_keys.json", JWKSFile: "jwks.json"}, dataDir)
+	if err != nil {
+		t.Fatalf("Failed to build authentication: %v", err)
+	}
+	token := signJWT(map[string]any{"alg": "RS256", "kid": "key-1"}, map[string]any{"sub": "svc", "exp": now().Add(time.Hour).Unix(), "scope": "availability:read"})
+	for _, credential := range []string{"checkout-secret", token} {
+		if rec := serveWithAuth(auth, ScopeAvailabilityRead, "Bearer "+credential); rec.Code != http.StatusOK {
+			t.Errorf("Expected both kinds of credential accepted, got %d", rec.Code)
+		}
+	}
+
+	if _, err := NewAuthFromConfig(AuthConfig{Enabled: true, APIKeysFile: "missing.json"}, dataDir); err == nil {
+		t.Error("Expected an error for a missing API keys file")
+	}
+}
@@ -1,68 +1,650 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 )
 
+// Reason codes reported in Response.ReasonCode
+const (
+	ReasonSufficientStock   = "sufficient_stock"
+	ReasonInsufficientStock = "insufficient_stock"
+	ReasonOutOfStock        = "out_of_stock"
+	ReasonProductNotFound   = "product_not_found"
+	ReasonUnknownWarehouse  = "unknown_warehouse"
+	ReasonSourceUnavailable = "source_unavailable"
+	ReasonSourceTimeout     = "source_timeout"
+	ReasonUnknownProduct    = "unknown_product"
+	ReasonDiscontinued      = "discontinued"
+	ReasonPreOrder          = "pre_order"
+	ReasonPreOrderLimit     = "pre_order_limit_exceeded"
+	ReasonBackorderable     = "backorderable"
+	ReasonUnknownUnit       = "unknown_unit"
+	ReasonTenantMismatch    = "tenant_mismatch"
+)
+
+// Availability statuses reported in Response.Status
+const (
+	StatusAvailable     = "available"
+	StatusBackorderable = "backorderable"
+	StatusUnavailable   = "unavailable"
+)
+
 // AvailabilityService handles the business logic for checking product availability
 type AvailabilityService struct {
+	tenantID         string // tenant whose data the service holds; "" in single-tenant mode
 	inventoryAdapter InventoryAdapter
+	catalog          *ProductCatalog
+	inbound          *InboundSchedule
+	warehouses       *WarehouseRegistry
+	replenishment    *ReplenishmentPlan
+	bundles          *BundleCatalog
+	substitutions    *SubstitutionMap
+	allocations      *AllocationPlan
+	metrics          *Metrics
+
+	reserveRatio      float64 // share of stock always kept in reserve
+	weekendMultiplier int     // stock needed per ordered unit on weekends
+	minShelfLifeDays  int     // default for products that do not set their own
+}
+
+// Default business rule parameters
+const (
+	DefaultReserveRatio      = 0.10
+	DefaultWeekendMultiplier = 2
+)
+
+// ServiceOption configures optional collaborators of an AvailabilityService
+type ServiceOption func(*AvailabilityService)
+
+// WithTenant binds the service to one tenant; requests for any other tenant are refused
+func WithTenant(tenantID string) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.tenantID = tenantID
+	}
+}
+
+// WithProductCatalog makes the service check product lifecycle status before stock
+func WithProductCatalog(catalog *ProductCatalog) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.catalog = catalog
+	}
+}
+
+// WithInboundSchedule makes available-to-promise checks count inbound purchase orders
+func WithInboundSchedule(inbound *InboundSchedule) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.inbound = inbound
+	}
+}
+
+// WithWarehouseRegistry makes date estimates respect each warehouse's holidays and shipping cut-off
+func WithWarehouseRegistry(warehouses *WarehouseRegistry) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.warehouses = warehouses
+	}
+}
+
+// WithReplenishmentPlan lets date estimates fall back to replenishment lead times
+func WithReplenishmentPlan(replenishment *ReplenishmentPlan) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.replenishment = replenishment
+	}
+}
+
+// WithBundleCatalog lets requests name a bundle, checked against its components' stock
+func WithBundleCatalog(bundles *BundleCatalog) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.bundles = bundles
+	}
+}
+
+// WithSubstitutionMap makes unavailable responses suggest available alternatives
+func WithSubstitutionMap(substitutions *SubstitutionMap) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.substitutions = substitutions
+	}
+}
+
+// WithAllocationPlan limits each request to its channel's allocation plus the shared pool
+func WithAllocationPlan(allocations *AllocationPlan) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.allocations = allocations
+	}
+}
+
+// WithReserveRatio sets the share of stock always kept in reserve, e.g. 0.10
+func WithReserveRatio(ratio float64) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.reserveRatio = ratio
+	}
+}
+
+// WithWeekendMultiplier sets how many units must be in stock per unit ordered on weekends
+func WithWeekendMultiplier(multiplier int) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.weekendMultiplier = multiplier
+	}
+}
+
+// WithMinShelfLife sets the days a lot must remain good after the ship date to
+// count as available, for products without their own minimum shelf life
+func WithMinShelfLife(days int) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.minShelfLifeDays = days
+	}
+}
+
+// WithMetrics makes the service count its outcomes by warehouse and reason code
+func WithMetrics(metrics *Metrics) ServiceOption {
+	return func(s *AvailabilityService) {
+		s.metrics = metrics
+	}
 }
 
 // NewAvailabilityService creates a new availability service with the given inventory adapter
-func NewAvailabilityService(adapter InventoryAdapter) *AvailabilityService {
-	return &AvailabilityService{
-		inventoryAdapter: adapter,
+func NewAvailabilityService(adapter InventoryAdapter, options ...ServiceOption) *AvailabilityService {
+	service := &AvailabilityService{
+		inventoryAdapter:  adapter,
+		reserveRatio:      DefaultReserveRatio,
+		weekendMultiplier: DefaultWeekendMultiplier,
+	}
+	for _, option := range options {
+		option(service)
+	}
+	return service
+}
+
+// now returns the current time; tests replace it to pin the weekday
+var now = time.Now
+
+// today returns the server's current date at midnight UTC, comparable with
+// parsed YYYY-MM-DD dates
+func today() time.Time {
+	return todayIn(time.Local)
+}
+
+// todayIn returns the current date in loc at midnight UTC, comparable with
+// parsed YYYY-MM-DD dates
+func todayIn(loc *time.Location) time.Time {
+	t := now().In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// warehouseToday returns the current date where the warehouse is, or the
+// server's date for a warehouse the registry does not know
+func (s *AvailabilityService) warehouseToday(code string) time.Time {
+	if w, ok := s.lookupWarehouse(code); ok {
+		return todayIn(w.location)
+	}
+	return today()
+}
+
+// lookupWarehouse returns the registered warehouse with the given code
+func (s *AvailabilityService) lookupWarehouse(code string) (Warehouse, bool) {
+	if s.warehouses == nil {
+		return Warehouse{}, false
+	}
+	return s.warehouses.Lookup(code)
+}
+
+// warehouseLabel returns the metric label for a requested warehouse. Codes
+// the registry does not know are reported as "unknown", so clients cannot
+// create new series
+func (s *AvailabilityService) warehouseLabel(code string) string {
+	if _, ok := s.lookupWarehouse(code); !ok {
+		return "unknown"
+	}
+	return code
+}
+
+// availableAfterReserve returns the stock that can be sold after keeping the reserve buffer
+func (s *AvailabilityService) availableAfterReserve(stockLevel int) int {
+	reserveBuffer := float64(stockLevel) * s.reserveRatio
+	return stockLevel - int(reserveBuffer)
+}
+
+// requiredQuantity returns the stock needed for an order, which is a
+// multiple of the ordered quantity on weekends
+func (s *AvailabilityService) requiredQuantity(quantity int) int {
+	if isWeekend() {
+		return quantity * s.weekendMultiplier
 	}
+	return quantity
 }
 
 // isWeekend checks if today is Saturday or Sunday
 func isWeekend() bool {
-	today := time.Now().Weekday()
+	today := now().Weekday()
 	return today == time.Saturday || today == time.Sunday
 }
 
 // CheckAvailability implements the business logic for checking product availability
 // Business Rules:
-// 1. 10% reserve buffer is always kept from total stock
-// 2. Weekend orders require 2x the normal quantity in stock
+// 1. A reserve buffer (10% by default) is always kept from total stock
+// 2. Weekend orders require a multiple (2x by default) of the quantity in stock
 // 3. Returns availability status with detailed reason
-func (s *AvailabilityService) CheckAvailability(req Request) Response {
+// When a product catalog is configured, unknown and discontinued products are
+// rejected and pre-order products are checked against their pre-order limit
+// instead of stock. A request with a ship date is decided in available-to-promise
+// mode, counting inbound stock expected by that date. Products whose backorder
+// policy allows it are reported as backorderable when stock falls short.
+// Outcomes are counted when the service has metrics, and the decision is
+// traced when ctx carries a span
+func (s *AvailabilityService) CheckAvailability(ctx context.Context, req Request) Response {
+	ctx, span := startSpan(ctx, "availability.check", SpanKindInternal,
+		slog.String("tenant", s.tenantID),
+		slog.String("product_id", req.ProductID),
+		slog.String("warehouse", req.WarehouseLocation),
+		slog.Int("quantity", req.Quantity))
+	defer span.End()
+
+	response := s.checkAvailability(ctx, req)
+	span.SetAttributes(
+		slog.String("outcome", response.Status),
+		slog.String("reason_code", response.ReasonCode),
+		slog.Int("available_quantity", response.AvailableQuantity),
+		slog.Any("rules", s.rulesApplied(req, response)))
+	if s.metrics != nil {
+		s.metrics.ObserveCheck(s.tenantID, s.warehouseLabel(req.WarehouseLocation), response.ReasonCode)
+	}
+	slog.DebugContext(ctx, "availability decided",
+		"product_id", req.ProductID,
+		"warehouse", req.WarehouseLocation,
+		"quantity", req.Quantity,
+		"status", response.Status,
+		"reason_code", response.ReasonCode,
+		"available_quantity", response.AvailableQuantity)
+	return response
+}
+
+// checkAvailability decides a request for CheckAvailability
+func (s *AvailabilityService) checkAvailability(ctx context.Context, req Request) Response {
+	// A service only ever answers from its own tenant's data
+	if req.TenantID != s.tenantID {
+		return Response{
+			Status:     StatusUnavailable,
+			ReasonCode: ReasonTenantMismatch,
+			Reason:     "Request is not for this tenant",
+			Warehouse:  req.WarehouseLocation,
+		}
+	}
+
+	// Availability is computed in the product's base unit
+	requested := req
+	req, product, ok := s.toBaseUnit(req)
+	if !ok {
+		return Response{
+			Status:     StatusUnavailable,
+			ReasonCode: ReasonUnknownUnit,
+			Reason:     fmt.Sprintf("Product %s cannot be requested in unit %q", req.ProductID, req.Unit),
+			Warehouse:  req.WarehouseLocation,
+		}
+	}
+
+	response := s.evaluate(ctx, req)
+	if product.UnitOfMeasure != "" {
+		response.Unit = product.UnitOfMeasure
+		if requested.Unit != "" {
+			response.Unit = requested.Unit
+		}
+		response.Quantity = requested.Quantity
+		response.BaseUnit = product.UnitOfMeasure
+		response.BaseQuantity = req.Quantity
+	}
+
+	// Report the overall status and how the quantity splits, unless a
+	// backorder or pre-order decision already did
+	if response.Status == "" {
+		if response.Available {
+			response.Status = StatusAvailable
+			response.InStockQuantity = req.Quantity
+		} else {
+			response.Status = StatusUnavailable
+		}
+	}
+
+	// Suggest alternatives the customer could order instead
+	if response.Status == StatusUnavailable && s.substitutions != nil {
+		response.Substitutes = s.findSubstitutes(ctx, req, response.ReasonCode)
+	}
+
+	return response
+}
+
+// rulesApplied names the business rules that shaped a response, for tracing
+func (s *AvailabilityService) rulesApplied(req Request, response Response) []string {
+	rules := []string{}
+	switch response.ReasonCode {
+	case ReasonSufficientStock, ReasonInsufficientStock, ReasonOutOfStock, ReasonBackorderable:
+		if s.reserveRatio > 0 {
+			rules = append(rules, "reserve_buffer")
+		}
+		if isWeekend() && s.weekendMultiplier > 1 {
+			rules = append(rules, "weekend_multiplier")
+		}
+	case ReasonUnknownProduct, ReasonDiscontinued:
+		rules = append(rules, "catalog_status")
+	case ReasonPreOrder, ReasonPreOrderLimit:
+		rules = append(rules, "pre_order")
+	}
+	if response.BaseUnit != "" {
+		rules = append(rules, "unit_conversion")
+	}
+	if response.Bundle != nil {
+		rules = append(rules, "bundle")
+	}
+	if response.ChannelAllocation != nil {
+		rules = append(rules, "channel_allocation")
+	}
+	if response.ShortDatedQuantity > 0 {
+		rules = append(rules, "shelf_life")
+	}
+	if req.ShipDate != "" {
+		rules = append(rules, "available_to_promise")
+	}
+	if response.Status == StatusBackorderable {
+		rules = append(rules, "backorder")
+	}
+	if len(response.Substitutes) > 0 {
+		rules = append(rules, "substitutes")
+	}
+	return rules
+}
+
+// toBaseUnit converts the requested quantity into the product's base unit and
+// returns the catalog entry it used. ok is false if the product cannot be
+// requested in that unit; without a catalog only the default unit is known
+func (s *AvailabilityService) toBaseUnit(req Request) (Request, Product, bool) {
+	var product Product
+	if s.catalog != nil {
+		product, _ = s.catalog.Lookup(req.ProductID)
+	}
+	factor, ok := product.Factor(req.Unit)
+	if !ok {
+		return req, product, false
+	}
+	req.Quantity *= factor
+	req.Unit = ""
+	return req, product, true
+}
+
+// sellableStock returns the stock that can fill a request, in the product's
+// base unit. For stock tracked by lot, only lots expiring after the ship date
+// (today without one) plus the minimum shelf life count; the rest is
+// returned as short-dated
+func (s *AvailabilityService) sellableStock(req Request, productID string, info StockInfo) (stock, shortDated int) {
+	stock = info.StockLevel
+	if len(info.Lots) > 0 {
+		cutoff := s.warehouseToday(req.WarehouseLocation)
+		if req.ShipDate != "" {
+			cutoff, _ = time.Parse(time.DateOnly, req.ShipDate) // validated by the handler
+		}
+		cutoff = cutoff.AddDate(0, 0, s.minShelfLife(productID))
+		stock, shortDated = splitLotsByExpiry(info.Lots, cutoff)
+	}
+	return s.stockInBaseUnit(productID, stock), s.stockInBaseUnit(productID, shortDated)
+}
+
+// channelStock returns the part of the stock the request's channel can draw
+// on: its own allocation plus the shared pool. Requests without a channel
+// are not held to any allocation and draw on all stock. Stock is returned
+// unchanged if no allocation rules apply
+func (s *AvailabilityService) channelStock(req Request, productID string, stock int) (int, *ChannelAllocation) {
+	if s.allocations == nil || req.Channel == "" {
+		return stock, nil
+	}
+	allocated, shared, ok := s.allocations.Allocate(productID, req.WarehouseLocation, req.Channel, stock)
+	if !ok {
+		return stock, nil
+	}
+	return allocated + shared, &ChannelAllocation{
+		Channel:           req.Channel,
+		AllocatedQuantity: allocated,
+		SharedQuantity:    shared,
+	}
+}
+
+// minShelfLife returns the days a lot of the product must remain good after shipping
+func (s *AvailabilityService) minShelfLife(productID string) int {
+	if s.catalog != nil {
+		if product, ok := s.catalog.Lookup(productID); ok && product.MinShelfLifeDays > 0 {
+			return product.MinShelfLifeDays
+		}
+	}
+	return s.minShelfLifeDays
+}
+
+// stockInBaseUnit converts a stock level from the product's stock unit into its base unit
+func (s *AvailabilityService) stockInBaseUnit(productID string, stockLevel int) int {
+	if s.catalog == nil {
+		return stockLevel
+	}
+	product, _ := s.catalog.Lookup(productID)
+	factor, _ := product.Factor(product.StockUnit)
+	return stockLevel * factor
+}
+
+// expectedInbound returns the product's inbound shipments at the warehouse
+// that are still due. Shipments expected before the warehouse's today are
+// left out: once received they are part of stock on hand, and late ones
+// cannot be promised. Purchase orders are counted in the stock unit, like
+// stock levels, so their quantities are converted into the base unit
+func (s *AvailabilityService) expectedInbound(productID, warehouse string) []InboundShipment {
+	if s.inbound == nil {
+		return nil
+	}
+	today := s.warehouseToday(warehouse)
+	var due []InboundShipment
+	for _, shipment := range s.inbound.Expected(productID, warehouse) {
+		if shipment.expected.Before(today) {
+			continue
+		}
+		shipment.Quantity = s.stockInBaseUnit(productID, shipment.Quantity)
+		due = append(due, shipment)
+	}
+	return due
+}
+
+// findSubstitutes returns the configured alternatives that are in stock at the
+// same warehouse in the requested quantity. Only product outcomes qualify;
+// when the lookup itself failed there is nothing to substitute for.
+// Alternatives counted in a different base unit are skipped, as the
+// requested quantity has no meaning for them
+func (s *AvailabilityService) findSubstitutes(ctx context.Context, req Request, reasonCode string) []Substitute {
+	switch reasonCode {
+	case ReasonInsufficientStock, ReasonOutOfStock, ReasonProductNotFound, ReasonDiscontinued:
+	default:
+		return nil
+	}
+
+	var substitutes []Substitute
+	for _, productID := range s.substitutions.Substitutes(req.ProductID) {
+		if ctx.Err() != nil {
+			break // out of time; answer with what was found
+		}
+		if !s.sameBaseUnit(req.ProductID, productID) {
+			continue
+		}
+		alternative := req
+		alternative.ProductID = productID
+		resp := s.evaluate(ctx, alternative)
+		if resp.ReasonCode != ReasonSufficientStock {
+			continue
+		}
+		substitutes = append(substitutes, Substitute{
+			ProductID:         productID,
+			AvailableQuantity: resp.AvailableQuantity,
+		})
+	}
+	return substitutes
+}
+
+// sameBaseUnit reports whether two products are counted in the same base
+// unit. Products missing from the catalog use the default unit
+func (s *AvailabilityService) sameBaseUnit(productID, otherID string) bool {
+	if s.catalog == nil {
+		return true
+	}
+	product, _ := s.catalog.Lookup(productID)
+	other, _ := s.catalog.Lookup(otherID)
+	return product.UnitOfMeasure == other.UnitOfMeasure
+}
+
+// evaluate applies the business rules to a request
+func (s *AvailabilityService) evaluate(ctx context.Context, req Request) Response {
 	response := Response{
 		Warehouse: req.WarehouseLocation,
 	}
 
+	// Bundles are built from component stock
+	if s.bundles != nil {
+		if bundle, ok := s.bundles.Lookup(req.ProductID); ok {
+			return s.checkBundle(ctx, req, bundle, response)
+		}
+	}
+
+	// Consult the catalog for the product's lifecycle status
+	var product Product
+	if s.catalog != nil {
+		var ok bool
+		product, ok = s.catalog.Lookup(req.ProductID)
+		switch {
+		case !ok:
+			response.ReasonCode = ReasonUnknownProduct
+			response.Reason = "Product not found in catalog"
+			return response
+		case product.Status == ProductDiscontinued:
+			response.ReasonCode = ReasonDiscontinued
+			response.Reason = "Product has been discontinued"
+			return response
+		case product.Status == ProductPreOrder:
+			return checkPreOrder(req, product, response)
+		}
+	}
+
 	// Get stock level from the inventory adapter
-	stockLevel, err := s.inventoryAdapter.GetStockLevel(req.ProductID, req.WarehouseLocation)
+	info, err := s.lookupStock(ctx, req.ProductID, req.WarehouseLocation)
 	if err != nil {
 		response.Available = false
 		response.AvailableQuantity = 0
-		response.Reason = "Product not found in specified warehouse"
+		response.ReasonCode, response.Reason = lookupFailureReason(err)
+		return response
+	}
+	stockLevel, shortDated := s.sellableStock(req, req.ProductID, info)
+	response.ShortDatedQuantity = shortDated
+	stockLevel, response.ChannelAllocation = s.channelStock(req, req.ProductID, stockLevel)
+
+	// Report which source answered and how old its data is
+	if info.Source != "" {
+		response.Source = &SourceInfo{
+			Name:       info.Source,
+			AsOf:       info.AsOf,
+			AgeSeconds: int64(now().Sub(info.AsOf).Seconds()),
+		}
+	}
+
+	// Determine required quantity based on weekend logic
+	requiredQuantity := s.requiredQuantity(req.Quantity)
+
+	// With a desired ship date, inbound stock expected by then counts too
+	if req.ShipDate != "" {
+		response = s.checkAvailableToPromise(req, stockLevel, requiredQuantity, response)
+	} else {
+		response = s.checkOnHand(req, stockLevel, requiredQuantity, response)
+	}
+
+	// Tell the customer when an unavailable product can be expected
+	if !response.Available {
+		response.EarliestAvailableDate = s.earliestAvailableDate(req, stockLevel)
+		response = applyBackorderPolicy(req, product, requiredQuantity, response)
+	}
+
+	return response
+}
+
+// lookupStock asks the inventory adapter for stock and logs and traces the
+// lookup against the request. Sources that fail to answer are logged as
+// warnings and mark the span as failed
+func (s *AvailabilityService) lookupStock(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	ctx, span := startSpan(ctx, "inventory.lookup", SpanKindInternal,
+		slog.String("product_id", productID),
+		slog.String("warehouse", warehouse))
+	defer span.End()
+
+	start := time.Now()
+	info, err := lookupStock(ctx, s.inventoryAdapter, productID, warehouse)
+	recordLookup(span, info, err)
+	attrs := []any{
+		"product_id", productID,
+		"warehouse", warehouse,
+		"duration_ms", durationMillis(time.Since(start)),
+	}
+	switch {
+	case err == nil:
+		slog.DebugContext(ctx, "inventory lookup", append(attrs, "stock_level", info.StockLevel, "source", info.Source)...)
+	case isAuthoritative(err):
+		slog.DebugContext(ctx, "inventory lookup", append(attrs, "error", err)...)
+	default:
+		slog.WarnContext(ctx, "inventory lookup failed", append(attrs, "error", err)...)
+	}
+	return info, err
+}
+
+// applyBackorderPolicy splits a request that stock cannot cover into an
+// in-stock and a backordered portion, and accepts it as backorderable if the
+// product's policy allows that many units on backorder. Like pre-orders,
+// backorderable requests are available: the order can be placed
+func applyBackorderPolicy(req Request, product Product, requiredQuantity int, response Response) Response {
+	if response.ReasonCode != ReasonInsufficientStock && response.ReasonCode != ReasonOutOfStock {
 		return response
 	}
 
+	// On weekends every ordered unit needs more than one unit in stock
+	multiplier := requiredQuantity / req.Quantity
+	inStock := min(req.Quantity, response.AvailableQuantity/multiplier)
+	backordered := req.Quantity - inStock
+	response.InStockQuantity = inStock
+
+	switch product.BackorderPolicy {
+	case BackorderUnlimited:
+	case BackorderLimited:
+		if backordered > product.BackorderLimit {
+			return response
+		}
+	default:
+		return response
+	}
+
+	response.Available = true
+	response.Status = StatusBackorderable
+	response.BackorderedQuantity = backordered
+	response.ReasonCode = ReasonBackorderable
+	response.Reason = fmt.Sprintf("Backorderable (%d units in stock, %d units on backorder)", inStock, backordered)
+	return response
+}
+
+// checkOnHand decides a request against stock on hand after the reserve buffer
+func (s *AvailabilityService) checkOnHand(req Request, stockLevel, requiredQuantity int, response Response) Response {
 	// Calculate available stock after applying 10% reserve buffer
-	reserveBuffer := float64(stockLevel) * 0.10
-	availableStock := stockLevel - int(reserveBuffer)
+	availableStock := s.availableAfterReserve(stockLevel)
 	response.AvailableQuantity = availableStock
 
 	// Check if stock is zero
 	if stockLevel == 0 {
 		response.Available = false
+		response.ReasonCode = ReasonOutOfStock
 		response.Reason = "Product is out of stock"
 		return response
 	}
 
-	// Determine required quantity based on weekend logic
-	requiredQuantity := req.Quantity
-	if isWeekend() {
-		requiredQuantity = req.Quantity * 2
-	}
-
 	// Check if we have enough available stock
 	if availableStock >= requiredQuantity {
 		response.Available = true
+		response.ReasonCode = ReasonSufficientStock
 		if isWeekend() {
 			response.Reason = fmt.Sprintf("Sufficient stock available (weekend: requires %d units in stock for %d order)", requiredQuantity, req.Quantity)
 		} else {
@@ -70,6 +652,7 @@ func (s *AvailabilityService) CheckAvailability(req Request) Response {
 		}
 	} else {
 		response.Available = false
+		response.ReasonCode = ReasonInsufficientStock
 		if isWeekend() {
 			response.Reason = fmt.Sprintf("Insufficient stock (weekend: requires %d units, only %d available after reserve)", requiredQuantity, availableStock)
 		} else {
@@ -79,3 +662,236 @@ func (s *AvailabilityService) CheckAvailability(req Request) Response {
 
 	return response
 }
+
+// checkAvailableToPromise decides a request with a desired ship date. Stock
+// on hand plus inbound shipments expected on or before the ship date must
+// cover the required quantity after the reserve buffer. The response also
+// reports the earliest date the full quantity can be promised.
+func (s *AvailabilityService) checkAvailableToPromise(req Request, stockLevel, requiredQuantity int, response Response) Response {
+	shipDate, _ := time.Parse(time.DateOnly, req.ShipDate) // validated by the handler
+
+	inbound := s.expectedInbound(req.ProductID, req.WarehouseLocation)
+
+	inboundQuantity := 0
+	for _, shipment := range inbound {
+		if shipment.expected.After(shipDate) {
+			break
+		}
+		inboundQuantity += shipment.Quantity
+	}
+
+	availableStock := s.availableAfterReserve(stockLevel + inboundQuantity)
+	response.AvailableQuantity = availableStock
+	response.ShipDate = req.ShipDate
+	response.InboundQuantity = inboundQuantity
+	if date, ok := s.earliestPromiseDate(s.warehouseToday(req.WarehouseLocation), stockLevel, requiredQuantity, inbound); ok {
+		response.EarliestPromiseDate = date.Format(time.DateOnly)
+	}
+
+	switch {
+	case availableStock >= requiredQuantity:
+		response.Available = true
+		response.ReasonCode = ReasonSufficientStock
+		response.Reason = fmt.Sprintf("Sufficient stock available by %s (requires %d units, %d on hand and %d inbound)", req.ShipDate, requiredQuantity, stockLevel, inboundQuantity)
+	case stockLevel+inboundQuantity == 0:
+		response.Available = false
+		response.ReasonCode = ReasonOutOfStock
+		response.Reason = fmt.Sprintf("Product is out of stock with no inbound stock expected by %s", req.ShipDate)
+	default:
+		response.Available = false
+		response.ReasonCode = ReasonInsufficientStock
+		response.Reason = fmt.Sprintf("Insufficient stock by %s (requires %d units, only %d available after reserve including %d inbound)", req.ShipDate, requiredQuantity, availableStock, inboundQuantity)
+	}
+
+	return response
+}
+
+// earliestPromiseDate walks inbound shipments in date order and returns the
+// first date from date on which stock after reserve covers the required quantity
+func (s *AvailabilityService) earliestPromiseDate(date time.Time, stockLevel, requiredQuantity int, inbound []InboundShipment) (time.Time, bool) {
+	if s.availableAfterReserve(stockLevel) >= requiredQuantity {
+		return date, true
+	}
+
+	projected := stockLevel
+	for _, shipment := range inbound {
+		projected += shipment.Quantity
+		if s.availableAfterReserve(projected) >= requiredQuantity {
+			if shipment.expected.After(date) {
+				date = shipment.expected
+			}
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// earliestAvailableDate estimates when the requested quantity can ship from
+// the warehouse, or returns nil if no replenishment is scheduled.
+//
+// Candidates are the date inbound shipments cover the quantity and today plus
+// the replenishment lead time; the earlier one wins. The result is moved to
+// the next shipping day, skipping weekends, warehouse holidays and today once
+// the shipping cut-off has passed. Because that day is never a weekend the
+// plain requested quantity is used, not the weekend requirement.
+func (s *AvailabilityService) earliestAvailableDate(req Request, stockLevel int) *string {
+	var warehouse *Warehouse
+	if w, ok := s.lookupWarehouse(req.WarehouseLocation); ok {
+		warehouse = &w
+	}
+	// Dates count from the warehouse's own calendar day
+	day := s.warehouseToday(req.WarehouseLocation)
+
+	var candidates []time.Time
+
+	if s.inbound != nil {
+		inbound := s.expectedInbound(req.ProductID, req.WarehouseLocation)
+		if date, ok := s.earliestPromiseDate(day, stockLevel, req.Quantity, inbound); ok {
+			candidates = append(candidates, date)
+		}
+	}
+	if s.replenishment != nil {
+		if days, ok := s.replenishment.LeadTime(req.ProductID, req.WarehouseLocation); ok {
+			candidates = append(candidates, day.AddDate(0, 0, days))
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	earliest := candidates[0]
+	for _, date := range candidates[1:] {
+		if date.Before(earliest) {
+			earliest = date
+		}
+	}
+
+	formatted := nextShippingDay(earliest, warehouse).Format(time.DateOnly)
+	return &formatted
+}
+
+// checkBundle decides a request for a bundle. Each component is evaluated
+// like a product request for its share of the bundles, so its catalog
+// status, ship date and channel apply; its available quantity then limits
+// how many bundles can be built. The smallest result is the buildable
+// quantity and names the limiting component
+func (s *AvailabilityService) checkBundle(ctx context.Context, req Request, bundle Bundle, response Response) Response {
+	result := &BundleAvailability{
+		BundleID:          bundle.BundleID,
+		BuildableQuantity: -1,
+	}
+
+	// Determine required quantity based on weekend logic
+	requiredQuantity := s.requiredQuantity(req.Quantity)
+
+	var dates []*string // earliest dates of the components that fall short
+	for _, component := range bundle.Components {
+		componentReq := req
+		componentReq.ProductID = component.ProductID
+		componentReq.Quantity = req.Quantity * component.Quantity
+		componentResp := s.evaluate(ctx, componentReq)
+
+		switch componentResp.ReasonCode {
+		case ReasonSufficientStock, ReasonInsufficientStock, ReasonOutOfStock, ReasonBackorderable:
+		case ReasonPreOrder, ReasonPreOrderLimit:
+			// Nothing can be built from a component that is not released yet
+			componentResp.AvailableQuantity = 0
+			componentResp.EarliestAvailableDate = nil
+		default:
+			response.ReasonCode = componentResp.ReasonCode
+			response.Reason = fmt.Sprintf("Bundle component %s: %s", component.ProductID, componentResp.Reason)
+			return response
+		}
+
+		buildable := componentResp.AvailableQuantity / component.Quantity
+		result.Components = append(result.Components, ComponentAvailability{
+			ProductID:         component.ProductID,
+			QuantityPerBundle: component.Quantity,
+			AvailableQuantity: componentResp.AvailableQuantity,
+			BuildableQuantity: buildable,
+		})
+		if result.BuildableQuantity < 0 || buildable < result.BuildableQuantity {
+			result.BuildableQuantity = buildable
+			result.LimitingComponent = component.ProductID
+		}
+		if buildable < requiredQuantity {
+			dates = append(dates, componentResp.EarliestAvailableDate)
+		}
+	}
+
+	response.Bundle = result
+	response.AvailableQuantity = result.BuildableQuantity
+	response.ShipDate = req.ShipDate
+
+	switch {
+	case result.BuildableQuantity >= requiredQuantity:
+		response.Available = true
+		response.ReasonCode = ReasonSufficientStock
+		response.Reason = fmt.Sprintf("Sufficient component stock (requires %d bundles, %d buildable)", requiredQuantity, result.BuildableQuantity)
+	case result.BuildableQuantity == 0:
+		response.ReasonCode = ReasonOutOfStock
+		response.Reason = fmt.Sprintf("Bundle cannot be built (limited by %s)", result.LimitingComponent)
+	default:
+		response.ReasonCode = ReasonInsufficientStock
+		response.Reason = fmt.Sprintf("Insufficient component stock (requires %d bundles, only %d buildable, limited by %s)", requiredQuantity, result.BuildableQuantity, result.LimitingComponent)
+	}
+	if !response.Available {
+		response.EarliestAvailableDate = latestDate(dates)
+	}
+
+	return response
+}
+
+// latestDate returns the latest of the dates, or nil if any is unknown: a
+// bundle can only be built once every short component has arrived
+func latestDate(dates []*string) *string {
+	var latest *string
+	for _, date := range dates {
+		if date == nil {
+			return nil
+		}
+		// YYYY-MM-DD dates order like strings
+		if latest == nil || *date > *latest {
+			latest = date
+		}
+	}
+	return latest
+}
+
+// checkPreOrder decides pre-order requests. Pre-order products have no stock
+// to check; they are accepted up to the product's per-order limit
+func checkPreOrder(req Request, product Product, response Response) Response {
+	if product.PreorderLimit > 0 && req.Quantity > product.PreorderLimit {
+		response.Available = false
+		response.ReasonCode = ReasonPreOrderLimit
+		response.Reason = fmt.Sprintf("Pre-order limit exceeded (maximum %d units per order)", product.PreorderLimit)
+		return response
+	}
+
+	// Pre-orders are accepted like backorders: nothing ships from stock
+	response.Available = true
+	response.Status = StatusBackorderable
+	response.BackorderedQuantity = req.Quantity
+	response.ReasonCode = ReasonPreOrder
+	if product.ReleaseDate != "" {
+		response.Reason = fmt.Sprintf("Available for pre-order (ships from %s)", product.ReleaseDate)
+	} else {
+		response.Reason = "Available for pre-order"
+	}
+	return response
+}
+
+// lookupFailureReason translates an adapter error into a reason code and message.
+// Errors the adapter did not classify are treated as the source being unavailable
+func lookupFailureReason(err error) (code, reason string) {
+	switch {
+	case errors.Is(err, ErrProductNotFound):
+		return ReasonProductNotFound, "Product not found in specified warehouse"
+	case errors.Is(err, ErrUnknownWarehouse):
+		return ReasonUnknownWarehouse, "Warehouse not known to the inventory source"
+	case errors.Is(err, ErrTimeout):
+		return ReasonSourceTimeout, "Inventory source did not respond in time"
+	default:
+		return ReasonSourceUnavailable, "Inventory data temporarily unavailable"
+	}
+}
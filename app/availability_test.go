@@ -1,10 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"testing"
+	"time"
 )
 
+// TestMain pins the clock to a weekday so the weekend rule does not make
+// results depend on the day the suite runs
+func TestMain(m *testing.M) {
+	now = func() time.Time {
+		return time.Date(2024, time.January, 10, 12, 0, 0, 0, time.UTC) // Wednesday
+	}
+	os.Exit(m.Run())
+}
+
 // MockInventoryAdapter is a test double for the InventoryAdapter interface
 type MockInventoryAdapter struct {
 	inventory map[string]map[string]int // productID -> warehouse -> stock
@@ -43,6 +55,9 @@ func NewMockInventoryAdapter() *MockInventoryAdapter {
 			"PROD-505": {
 				"US-NewYork": 5, // Very low stock
 			},
+			"PROD-909": {
+				"DE-Berlin": 5, // Counted in cases of 24
+			},
 		},
 	}
 }
@@ -51,13 +66,13 @@ func (m *MockInventoryAdapter) LoadInventory() error {
 	return nil
 }
 
-func (m *MockInventoryAdapter) GetStockLevel(productID, warehouse string) (int, error) {
+func (m *MockInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
 	if warehouses, ok := m.inventory[productID]; ok {
 		if stock, ok := warehouses[warehouse]; ok {
 			return stock, nil
 		}
 	}
-	return 0, fmt.Errorf("product %s not found in warehouse %s", productID, warehouse)
+	return 0, fmt.Errorf("%w: %s in warehouse %s", ErrProductNotFound, productID, warehouse)
 }
 
 func TestCheckAvailability_SufficientStock(t *testing.T) {
@@ -70,7 +85,7 @@ func TestCheckAvailability_SufficientStock(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true, got false. Reason: %s", resp.Reason)
@@ -90,7 +105,7 @@ func TestCheckAvailability_OutOfStock(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false for out of stock item")
@@ -114,7 +129,7 @@ func TestCheckAvailability_InsufficientStock(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false for insufficient stock")
@@ -135,7 +150,7 @@ func TestCheckAvailability_ExactlyAtThreshold(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true for exactly 9 units, got false. Available: %d", resp.AvailableQuantity)
@@ -153,7 +168,7 @@ func TestCheckAvailability_JustAboveThreshold(t *testing.T) {
 		WarehouseLocation: "US-NewYork",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true, got false. Available quantity: %d", resp.AvailableQuantity)
@@ -171,7 +186,7 @@ func TestCheckAvailability_VeryLowStock(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true for 1 unit when stock=1, got false")
@@ -189,7 +204,7 @@ func TestCheckAvailability_VeryLowStockInsufficientQuantity(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false when requesting 2 units but only 1 available")
@@ -207,7 +222,7 @@ func TestCheckAvailability_HighStock(t *testing.T) {
 		WarehouseLocation: "UK-London",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true for large order with high stock, got false")
@@ -227,7 +242,7 @@ func TestCheckAvailability_ProductNotFound(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false for non-existent product")
@@ -247,7 +262,7 @@ func TestCheckAvailability_WrongWarehouse(t *testing.T) {
 		WarehouseLocation: "UK-London", // PROD-123 not in UK-London
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false for product in wrong warehouse")
@@ -265,7 +280,7 @@ func TestCheckAvailability_LargeInventory(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if !resp.Available {
 		t.Errorf("Expected available=true, got false. Available: %d", resp.AvailableQuantity)
@@ -284,7 +299,7 @@ func TestCheckAvailability_JustOverAvailable(t *testing.T) {
 		WarehouseLocation: "DE-Berlin",
 	}
 
-	resp := service.CheckAvailability(req)
+	resp := service.CheckAvailability(t.Context(), req)
 
 	if resp.Available {
 		t.Error("Expected available=false when requesting more than available")
@@ -316,7 +331,7 @@ func TestCheckAvailability_ReserveBufferCalculation(t *testing.T) {
 			WarehouseLocation: tt.warehouse,
 		}
 
-		resp := service.CheckAvailability(req)
+		resp := service.CheckAvailability(t.Context(), req)
 
 		if resp.AvailableQuantity != tt.expected {
 			t.Errorf("Product %s in %s: expected available_quantity=%d, got %d (stock=%d)",
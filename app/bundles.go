@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// BundleComponent is one line of a bundle's bill of materials
+type BundleComponent struct {
+	ProductID string `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+// Bundle is a kit sold under its own ID and built from component products
+type Bundle struct {
+	BundleID   string            `json:"bundle_id"`
+	Name       string            `json:"name"`
+	Components []BundleComponent `json:"components"`
+}
+
+// BundleCatalog holds bundle definitions, loaded from a JSON file
+type BundleCatalog struct {
+	filePath string
+	bundles  map[string]Bundle
+}
+
+// NewBundleCatalog creates a bundle catalog backed by the given JSON file
+func NewBundleCatalog(filePath string) *BundleCatalog {
+	return &BundleCatalog{
+		filePath: filePath,
+		bundles:  map[string]Bundle{},
+	}
+}
+
+// Load reads and validates the bundle definitions
+func (c *BundleCatalog) Load() error {
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read bundle catalog: %w", err)
+	}
+
+	var bundles []Bundle
+	err = json.Unmarshal(data, &bundles)
+	if err != nil {
+		return fmt.Errorf("failed to parse bundle catalog JSON: %w", err)
+	}
+
+	return c.setBundles(bundles)
+}
+
+// setBundles validates bundles and replaces the catalog with them
+func (c *BundleCatalog) setBundles(bundles []Bundle) error {
+	byID := make(map[string]Bundle, len(bundles))
+	for _, b := range bundles {
+		if b.BundleID == "" {
+			return fmt.Errorf("bundle without bundle_id in %s", c.filePath)
+		}
+		if _, ok := byID[b.BundleID]; ok {
+			return fmt.Errorf("duplicate bundle %s", b.BundleID)
+		}
+		if len(b.Components) == 0 {
+			return fmt.Errorf("bundle %s has no components", b.BundleID)
+		}
+		listed := make(map[string]bool, len(b.Components))
+		for _, component := range b.Components {
+			if component.ProductID == "" || component.Quantity <= 0 {
+				return fmt.Errorf("bundle %s: components need a product_id and a positive quantity", b.BundleID)
+			}
+			// A second line would be checked against the same stock on its own
+			if listed[component.ProductID] {
+				return fmt.Errorf("bundle %s lists component %s twice", b.BundleID, component.ProductID)
+			}
+			listed[component.ProductID] = true
+		}
+		byID[b.BundleID] = b
+	}
+	for _, b := range byID {
+		for _, component := range b.Components {
+			if _, ok := byID[component.ProductID]; ok {
+				return fmt.Errorf("bundle %s: component %s is itself a bundle", b.BundleID, component.ProductID)
+			}
+		}
+	}
+
+	c.bundles = byID
+	return nil
+}
+
+// checkCatalog rejects bundles whose ID is also a catalog product, as
+// requests for that ID would only ever see the bundle
+func (c *BundleCatalog) checkCatalog(catalog *ProductCatalog) error {
+	for id := range c.bundles {
+		if _, ok := catalog.Lookup(id); ok {
+			return fmt.Errorf("bundle %s has the ID of a catalog product", id)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the bundle with the given ID
+func (c *BundleCatalog) Lookup(bundleID string) (Bundle, bool) {
+	b, ok := c.bundles[bundleID]
+	return b, ok
+}
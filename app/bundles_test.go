@@ -0,0 +1,167 @@
+package main
+
+import (
+	"testing"
+)
+
+// newBundleService returns a service over the mock adapter with the shipped bundles.json
+func newBundleService(t *testing.T) *AvailabilityService {
+	t.Helper()
+	bundles := NewBundleCatalog("bundles.json")
+	if err := bundles.Load(); err != nil {
+		t.Fatalf("Failed to load bundles: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithBundleCatalog(bundles))
+}
+
+func TestCheckAvailability_BundleBuildableQuantity(t *testing.T) {
+	service := newBundleService(t)
+
+	// BUNDLE-001 = 1x PROD-123 (90 available) + 2x PROD-456 (23 available)
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-001", Quantity: 11, WarehouseLocation: "DE-Berlin"})
+
+	if !resp.Available {
+		t.Errorf("Expected available=true for 11 bundles, got false. Reason: %s", resp.Reason)
+	}
+	if resp.Bundle == nil {
+		t.Fatal("Expected bundle details in response")
+	}
+	if resp.Bundle.BuildableQuantity != 11 || resp.AvailableQuantity != 11 {
+		t.Errorf("Expected 11 buildable, got %d (available_quantity=%d)", resp.Bundle.BuildableQuantity, resp.AvailableQuantity)
+	}
+	if resp.Bundle.LimitingComponent != "PROD-456" {
+		t.Errorf("Expected PROD-456 to be limiting, got %s", resp.Bundle.LimitingComponent)
+	}
+	if len(resp.Bundle.Components) != 2 || resp.Bundle.Components[0].BuildableQuantity != 90 {
+		t.Errorf("Unexpected component details: %+v", resp.Bundle.Components)
+	}
+}
+
+func TestCheckAvailability_BundleInsufficient(t *testing.T) {
+	service := newBundleService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-001", Quantity: 12, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available || resp.ReasonCode != ReasonInsufficientStock {
+		t.Errorf("Expected insufficient stock for 12 bundles, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_BundleComponentMissing(t *testing.T) {
+	service := newBundleService(t)
+
+	// PROD-123 is not stocked in UK-London
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-001", Quantity: 1, WarehouseLocation: "UK-London"})
+
+	if resp.Available || resp.ReasonCode != ReasonProductNotFound {
+		t.Errorf("Expected product_not_found for missing component, got %+v", resp)
+	}
+	if resp.Reason != "Bundle component PROD-123: Product not found in specified warehouse" {
+		t.Errorf("Unexpected reason: %s", resp.Reason)
+	}
+}
+
+func TestBundleCatalog_RejectsEmptyBundle(t *testing.T) {
+	err := NewBundleCatalog("").setBundles([]Bundle{{BundleID: "BUNDLE-X"}})
+	if err == nil {
+		t.Error("Expected bundle without components to be rejected")
+	}
+}
+
+func TestBundleCatalog_RejectsInvalidBundles(t *testing.T) {
+	tests := []struct {
+		name    string
+		bundles []Bundle
+	}{
+		{"component listed twice", []Bundle{
+			{BundleID: "BUNDLE-X", Components: []BundleComponent{{"PROD-123", 1}, {"PROD-456", 1}, {"PROD-123", 1}}},
+		}},
+		{"bundle of bundles", []Bundle{
+			{BundleID: "BUNDLE-X", Components: []BundleComponent{{"PROD-123", 1}}},
+			{BundleID: "BUNDLE-Y", Components: []BundleComponent{{"BUNDLE-X", 2}}},
+		}},
+	}
+
+	for _, tt := range tests {
+		if err := NewBundleCatalog("").setBundles(tt.bundles); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestBundleCatalog_RejectsProductID(t *testing.T) {
+	bundles := NewBundleCatalog("")
+	if err := bundles.setBundles([]Bundle{{BundleID: "PROD-123", Components: []BundleComponent{{"PROD-456", 1}}}}); err != nil {
+		t.Fatalf("Failed to set bundles: %v", err)
+	}
+	catalog := NewProductCatalog("products.json")
+	if err := catalog.Load(); err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+
+	if err := bundles.checkCatalog(catalog); err == nil {
+		t.Error("Expected a bundle shadowing a catalog product to be rejected")
+	}
+}
+
+// newComponentStatusService returns a service where BUNDLE-X is built from
+// PROD-123, which is active, and PROD-456 in the given catalog status
+func newComponentStatusService(t *testing.T, status string) *AvailabilityService {
+	t.Helper()
+	bundles := NewBundleCatalog("")
+	if err := bundles.setBundles([]Bundle{{BundleID: "BUNDLE-X", Components: []BundleComponent{{"PROD-123", 1}, {"PROD-456", 1}}}}); err != nil {
+		t.Fatalf("Failed to set bundles: %v", err)
+	}
+	catalog := &ProductCatalog{products: map[string]Product{
+		"PROD-123": {ID: "PROD-123", Status: ProductActive},
+		"PROD-456": {ID: "PROD-456", Status: status},
+	}}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithBundleCatalog(bundles), WithProductCatalog(catalog))
+}
+
+func TestCheckAvailability_BundleComponentStatus(t *testing.T) {
+	tests := []struct {
+		status     string
+		reasonCode string
+	}{
+		{ProductDiscontinued, ReasonDiscontinued},
+		{ProductPreOrder, ReasonOutOfStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			service := newComponentStatusService(t, tt.status)
+
+			resp := service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-X", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+			if resp.Available || resp.ReasonCode != tt.reasonCode {
+				t.Errorf("Expected an unavailable bundle with reason %s, got %+v", tt.reasonCode, resp)
+			}
+		})
+	}
+}
+
+func TestCheckAvailability_BundleUsesComponentInbound(t *testing.T) {
+	bundles := NewBundleCatalog("")
+	if err := bundles.setBundles([]Bundle{{BundleID: "BUNDLE-X", Components: []BundleComponent{{"PROD-123", 1}, {"PROD-789", 1}}}}); err != nil {
+		t.Fatalf("Failed to set bundles: %v", err)
+	}
+	inbound := NewInboundSchedule("")
+	err := inbound.setShipments([]InboundShipment{
+		{PurchaseOrder: "PO-1", ProductID: "PROD-789", Warehouse: "DE-Berlin", Quantity: 30, ExpectedDate: "2024-01-15"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set shipments: %v", err)
+	}
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithBundleCatalog(bundles), WithInboundSchedule(inbound))
+
+	// PROD-789 has no stock on hand; 30 arrive on 2024-01-15, 27 after reserve
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-X", Quantity: 5, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-15"})
+	if !resp.Available || resp.Bundle == nil || resp.Bundle.BuildableQuantity != 27 {
+		t.Errorf("Expected 27 bundles buildable by the ship date, got %+v", resp)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "BUNDLE-X", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+	if resp.Available || resp.EarliestAvailableDate == nil || *resp.EarliestAvailableDate != "2024-01-15" {
+		t.Errorf("Expected the bundle to be available from 2024-01-15, got %+v", resp)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Product lifecycle statuses
+const (
+	ProductActive       = "active"
+	ProductDiscontinued = "discontinued"
+	ProductPreOrder     = "pre-order"
+)
+
+// Backorder policies
+const (
+	BackorderNone      = "none"
+	BackorderLimited   = "limited"
+	BackorderUnlimited = "unlimited"
+)
+
+// Product describes a catalog entry
+type Product struct {
+	ID            string `json:"product_id"`
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	UnitOfMeasure string `json:"unit_of_measure"`
+	Category      string `json:"category"`
+	ReleaseDate   string `json:"release_date,omitempty"`   // pre-order only, "2006-01-02"
+	PreorderLimit int    `json:"preorder_limit,omitempty"` // pre-order only, max units per order; 0 means unlimited
+
+	BackorderPolicy string `json:"backorder_policy,omitempty"` // none (default), limited or unlimited
+	BackorderLimit  int    `json:"backorder_limit,omitempty"`  // limited only, max units on backorder per order
+
+	// Units lists the other units the product can be requested in; quantities
+	// are converted to UnitOfMeasure, the base unit availability is computed in
+	Units     []UnitConversion `json:"units,omitempty"`
+	StockUnit string           `json:"stock_unit,omitempty"` // unit inventory counts stock in; defaults to the base unit
+
+	MinShelfLifeDays int `json:"min_shelf_life_days,omitempty"` // lots must remain good this long after shipping
+}
+
+// UnitConversion defines a unit as a number of base units, e.g. a case of 12
+type UnitConversion struct {
+	Unit   string `json:"unit"`
+	Factor int    `json:"factor"` // base units per unit
+}
+
+// Factor returns how many base units one unit is. The base unit, and an
+// empty unit meaning the base unit, have factor 1
+func (p Product) Factor(unit string) (int, bool) {
+	if unit == "" || unit == p.UnitOfMeasure {
+		return 1, true
+	}
+	for _, u := range p.Units {
+		if u.Unit == unit {
+			return u.Factor, true
+		}
+	}
+	return 0, false
+}
+
+// ProductCatalog holds the products the service knows about, loaded from a JSON file
+type ProductCatalog struct {
+	filePath string
+	products map[string]Product
+}
+
+// NewProductCatalog creates a catalog backed by the given JSON file
+func NewProductCatalog(filePath string) *ProductCatalog {
+	return &ProductCatalog{
+		filePath: filePath,
+		products: map[string]Product{},
+	}
+}
+
+// Load reads and validates the product definitions
+func (c *ProductCatalog) Load() error {
+	data, err := os.ReadFile(c.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read product catalog: %w", err)
+	}
+
+	var products []Product
+	err = json.Unmarshal(data, &products)
+	if err != nil {
+		return fmt.Errorf("failed to parse product catalog JSON: %w", err)
+	}
+
+	byID := make(map[string]Product, len(products))
+	for _, p := range products {
+		if p.ID == "" {
+			return fmt.Errorf("product without product_id in %s", c.filePath)
+		}
+		if _, ok := byID[p.ID]; ok {
+			return fmt.Errorf("duplicate product %s", p.ID)
+		}
+		switch p.Status {
+		case ProductActive, ProductDiscontinued, ProductPreOrder:
+		default:
+			return fmt.Errorf("product %s: invalid status %q", p.ID, p.Status)
+		}
+		switch p.BackorderPolicy {
+		case "", BackorderNone, BackorderUnlimited:
+		case BackorderLimited:
+			if p.BackorderLimit <= 0 {
+				return fmt.Errorf("product %s: limited backorder policy needs a positive backorder_limit", p.ID)
+			}
+		default:
+			return fmt.Errorf("product %s: invalid backorder_policy %q", p.ID, p.BackorderPolicy)
+		}
+		if p.ReleaseDate != "" {
+			if _, err := time.Parse(time.DateOnly, p.ReleaseDate); err != nil {
+				return fmt.Errorf("product %s: invalid release_date %q (want YYYY-MM-DD)", p.ID, p.ReleaseDate)
+			}
+		}
+		if p.MinShelfLifeDays < 0 {
+			return fmt.Errorf("product %s: min_shelf_life_days must not be negative", p.ID)
+		}
+		if err := validateUnits(p); err != nil {
+			return err
+		}
+		byID[p.ID] = p
+	}
+
+	c.products = byID
+	return nil
+}
+
+// validateUnits checks a product's unit conversions
+func validateUnits(p Product) error {
+	seen := map[string]bool{p.UnitOfMeasure: true}
+	for _, u := range p.Units {
+		if u.Unit == "" || u.Factor <= 0 {
+			return fmt.Errorf("product %s: units need a name and a positive factor", p.ID)
+		}
+		if seen[u.Unit] {
+			return fmt.Errorf("product %s: duplicate unit %q", p.ID, u.Unit)
+		}
+		seen[u.Unit] = true
+	}
+	if _, ok := p.Factor(p.StockUnit); !ok {
+		return fmt.Errorf("product %s: stock_unit %q is not one of its units", p.ID, p.StockUnit)
+	}
+	return nil
+}
+
+// Lookup returns the product with the given ID
+func (c *ProductCatalog) Lookup(productID string) (Product, bool) {
+	p, ok := c.products[productID]
+	return p, ok
+}
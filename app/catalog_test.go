@@ -0,0 +1,223 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newCatalogService returns a service over the mock adapter and the shipped products.json
+func newCatalogService(t *testing.T) *AvailabilityService {
+	t.Helper()
+	catalog := NewProductCatalog("products.json")
+	if err := catalog.Load(); err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithProductCatalog(catalog))
+}
+
+func TestCheckAvailability_ActiveProductUsesStock(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+
+	if !resp.Available || resp.ReasonCode != ReasonSufficientStock {
+		t.Errorf("Expected sufficient stock for active product, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_DiscontinuedProduct(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-606", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available {
+		t.Error("Expected available=false for discontinued product")
+	}
+	if resp.ReasonCode != ReasonDiscontinued {
+		t.Errorf("Expected reason_code=%s, got %s", ReasonDiscontinued, resp.ReasonCode)
+	}
+}
+
+func TestCheckAvailability_ProductNotInCatalog(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-12", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available || resp.ReasonCode != ReasonUnknownProduct {
+		t.Errorf("Expected unknown_product, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_PreOrder(t *testing.T) {
+	service := newCatalogService(t)
+
+	// PROD-707 is a pre-order product with no stock and a limit of 50 per order
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-707", Quantity: 50, WarehouseLocation: "DE-Berlin"})
+	if !resp.Available || resp.ReasonCode != ReasonPreOrder {
+		t.Errorf("Expected pre-order to be accepted, got %+v", resp)
+	}
+	if resp.Reason != "Available for pre-order (ships from 2026-12-01)" {
+		t.Errorf("Unexpected reason: %s", resp.Reason)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-707", Quantity: 51, WarehouseLocation: "DE-Berlin"})
+	if resp.Available || resp.ReasonCode != ReasonPreOrderLimit {
+		t.Errorf("Expected pre-order limit to be enforced, got %+v", resp)
+	}
+}
+
+func TestProductCatalog_RejectsInvalidStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	os.WriteFile(path, []byte(`[{"product_id": "PROD-1", "status": "retired"}]`), 0o644)
+
+	if err := NewProductCatalog(path).Load(); err == nil {
+		t.Error("Expected invalid status to be rejected")
+	}
+}
+
+func TestCheckAvailability_BackorderWithinLimit(t *testing.T) {
+	service := newCatalogService(t)
+
+	// PROD-456 at DE-Berlin: 23 available, backorders limited to 25 units
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-456", Quantity: 30, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Status != StatusBackorderable || resp.ReasonCode != ReasonBackorderable {
+		t.Errorf("Expected backorderable, got status=%s reason_code=%s", resp.Status, resp.ReasonCode)
+	}
+	if !resp.Available {
+		t.Error("Expected available=true for a backorderable request")
+	}
+	if resp.InStockQuantity != 23 || resp.BackorderedQuantity != 7 {
+		t.Errorf("Expected 23 in stock and 7 backordered, got %d and %d", resp.InStockQuantity, resp.BackorderedQuantity)
+	}
+}
+
+func TestCheckAvailability_BackorderOnWeekendSplitsQuantity(t *testing.T) {
+	saturday := time.Date(2024, time.January, 13, 12, 0, 0, 0, time.UTC)
+	restore := now
+	now = func() time.Time { return saturday }
+	defer func() { now = restore }()
+	service := newCatalogService(t)
+
+	// 20 units need 40 in stock on a weekend; the 23 available cover 11 units
+	req := Request{ProductID: "PROD-456", Quantity: 20, WarehouseLocation: "DE-Berlin"}
+	resp := service.CheckAvailability(t.Context(), req)
+
+	if resp.Status != StatusBackorderable || !resp.Available {
+		t.Fatalf("Expected an available backorderable response, got status=%s available=%v", resp.Status, resp.Available)
+	}
+	if resp.InStockQuantity+resp.BackorderedQuantity != req.Quantity {
+		t.Errorf("Expected in_stock_quantity + backordered_quantity = %d, got %d + %d", req.Quantity, resp.InStockQuantity, resp.BackorderedQuantity)
+	}
+	if resp.InStockQuantity != 11 {
+		t.Errorf("Expected 11 units in stock, got %d", resp.InStockQuantity)
+	}
+}
+
+func TestCheckAvailability_BackorderOverLimit(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-456", Quantity: 60, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Status != StatusUnavailable || resp.ReasonCode != ReasonInsufficientStock {
+		t.Errorf("Expected unavailable when backorder exceeds limit, got status=%s reason_code=%s", resp.Status, resp.ReasonCode)
+	}
+	if resp.InStockQuantity != 23 || resp.BackorderedQuantity != 0 {
+		t.Errorf("Expected 23 in stock and none backordered, got %d and %d", resp.InStockQuantity, resp.BackorderedQuantity)
+	}
+}
+
+func TestCheckAvailability_UnlimitedBackorderOutOfStock(t *testing.T) {
+	service := newCatalogService(t)
+
+	// PROD-789 is out of stock at DE-Berlin with unlimited backorders
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 100, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Status != StatusBackorderable {
+		t.Errorf("Expected backorderable, got %s", resp.Status)
+	}
+	if resp.InStockQuantity != 0 || resp.BackorderedQuantity != 100 {
+		t.Errorf("Expected 0 in stock and 100 backordered, got %d and %d", resp.InStockQuantity, resp.BackorderedQuantity)
+	}
+}
+
+func TestCheckAvailability_StatusWithoutBackorderPolicy(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+	if resp.Status != StatusAvailable || resp.InStockQuantity != 5 {
+		t.Errorf("Expected available with 5 in stock, got status=%s in_stock=%d", resp.Status, resp.InStockQuantity)
+	}
+
+	// PROD-101 has no backorder policy
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-101", Quantity: 20, WarehouseLocation: "DE-Berlin"})
+	if resp.Status != StatusUnavailable || resp.BackorderedQuantity != 0 {
+		t.Errorf("Expected unavailable without backorder, got status=%s backordered=%d", resp.Status, resp.BackorderedQuantity)
+	}
+}
+
+func TestCheckAvailability_ConvertsRequestedUnit(t *testing.T) {
+	service := newCatalogService(t)
+
+	// PROD-404 is also sold in boxes of 5; 180 are available
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-404", Quantity: 36, Unit: "box", WarehouseLocation: "DE-Berlin"})
+	if !resp.Available {
+		t.Errorf("Expected 36 boxes (180 each) to be available, got %+v", resp)
+	}
+	if resp.Unit != "box" || resp.Quantity != 36 || resp.BaseUnit != "each" || resp.BaseQuantity != 180 {
+		t.Errorf("Expected 36 box = 180 each echoed, got %s %d = %s %d", resp.Unit, resp.Quantity, resp.BaseUnit, resp.BaseQuantity)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-404", Quantity: 37, Unit: "box", WarehouseLocation: "DE-Berlin"})
+	if resp.Available || resp.ReasonCode != ReasonInsufficientStock {
+		t.Errorf("Expected 37 boxes (185 each) to be insufficient, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_ConvertsStockUnit(t *testing.T) {
+	service := newCatalogService(t)
+
+	// PROD-909 is stocked in cases of 24: 5 cases in DE-Berlin are 120 each, 108 after reserve
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-909", Quantity: 108, WarehouseLocation: "DE-Berlin"})
+	if !resp.Available || resp.AvailableQuantity != 108 {
+		t.Errorf("Expected 108 each available from five cases, got %+v", resp)
+	}
+	if resp.Unit != "each" || resp.BaseUnit != "each" || resp.BaseQuantity != 108 {
+		t.Errorf("Expected quantity echoed in the base unit, got %s %d = %s %d", resp.Unit, resp.Quantity, resp.BaseUnit, resp.BaseQuantity)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-909", Quantity: 5, Unit: "case", WarehouseLocation: "DE-Berlin"})
+	if resp.Available {
+		t.Errorf("Expected five full cases (120 each) to exceed the 108 available, got %+v", resp)
+	}
+
+	// Products without a stock_unit keep their stock counted in the base unit
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-202", Quantity: 450, WarehouseLocation: "UK-London"})
+	if !resp.Available || resp.AvailableQuantity != 450 {
+		t.Errorf("Expected 450 available from 500 each, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_UnknownUnit(t *testing.T) {
+	service := newCatalogService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, Unit: "pallet", WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available || resp.ReasonCode != ReasonUnknownUnit {
+		t.Errorf("Expected unknown_unit, got %+v", resp)
+	}
+	if code := statusForReason(resp.ReasonCode); code != 400 {
+		t.Errorf("Expected HTTP 400 for unknown unit, got %d", code)
+	}
+}
+
+func TestProductCatalog_RejectsUnknownStockUnit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "products.json")
+	os.WriteFile(path, []byte(`[{"product_id": "PROD-1", "status": "active", "unit_of_measure": "each", "stock_unit": "case"}]`), 0o644)
+
+	if err := NewProductCatalog(path).Load(); err == nil {
+		t.Error("Expected stock_unit without a conversion to be rejected")
+	}
+}
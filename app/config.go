@@ -0,0 +1,545 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Inventory adapter types
+const (
+	AdapterAuto      = "auto" // inventory.d when it exists, else inventory.json
+	AdapterFile      = "file"
+	AdapterDirectory = "directory"
+	AdapterAPI       = "api"
+)
+
+// Config is the effective server configuration. It is layered: defaults,
+// then a JSON or YAML config file, then environment variables, then
+// command-line flags, each overriding the ones before
+type Config struct {
+	Env         string          `json:"env"` // deployment environment, from GO_ENV
+	ListenAddr  string          `json:"listen_addr"`
+	DataDir     string          `json:"data_dir"`     // inventory and rule files
+	TenantsFile string          `json:"tenants_file"` // enables multi-tenant mode when the file exists
+	Server      ServerConfig    `json:"server"`
+	Inventory   InventoryConfig `json:"inventory"`
+	Rules       RulesConfig     `json:"rules"`
+	Log         LogConfig       `json:"log"`
+	Tracing     TracingConfig   `json:"tracing"`
+	Auth        AuthConfig      `json:"auth"`
+	RateLimit   RateLimitConfig `json:"rate_limit"`
+	CORS        CORSConfig      `json:"cors"`
+}
+
+// ServerConfig holds the HTTP server timeouts
+type ServerConfig struct {
+	ReadTimeout       Duration `json:"read_timeout"`
+	ReadHeaderTimeout Duration `json:"read_header_timeout"`
+	WriteTimeout      Duration `json:"write_timeout"`
+	IdleTimeout       Duration `json:"idle_timeout"`
+	ShutdownTimeout   Duration `json:"shutdown_timeout"` // how long in-flight requests may drain on SIGTERM/SIGINT
+	RequestTimeout    Duration `json:"request_timeout"`  // deadline for answering an API request, inventory lookups included
+}
+
+// InventoryConfig selects and tunes the inventory adapter
+type InventoryConfig struct {
+	Adapter          string   `json:"adapter"`        // auto, file, directory or api
+	File             string   `json:"file"`           // file adapter, relative to the data directory
+	Dir              string   `json:"dir"`            // directory adapter, relative to the data directory
+	WatchInterval    Duration `json:"watch_interval"` // directory adapter reload interval
+	APIURL           string   `json:"api_url"`
+	APITimeout       Duration `json:"api_timeout"`
+	FallbackFile     string   `json:"fallback_file"` // api adapter snapshot used while the API is down; empty disables
+	CacheTTL         Duration `json:"cache_ttl"`     // 0 disables the cache
+	CacheNegativeTTL Duration `json:"cache_negative_ttl"`
+	CacheMaxEntries  int      `json:"cache_max_entries"`
+	MaxAge           Duration `json:"max_age"` // readiness reports older data as stale; 0 disables the check
+}
+
+// RulesConfig holds the business rule parameters
+type RulesConfig struct {
+	ReserveRatio      float64 `json:"reserve_ratio"`
+	WeekendMultiplier int     `json:"weekend_multiplier"`
+	MinShelfLifeDays  int     `json:"min_shelf_life_days"`
+}
+
+// TracingConfig configures trace export over OTLP/HTTP
+type TracingConfig struct {
+	Endpoint       string   `json:"endpoint"` // OTLP traces URL, such as http://collector:4318/v1/traces; empty disables tracing
+	ServiceName    string   `json:"service_name"`
+	ExportInterval Duration `json:"export_interval"`
+}
+
+// AuthConfig configures authentication of the API and metrics endpoints.
+// Files are relative to the data directory
+type AuthConfig struct {
+	Enabled     bool   `json:"enabled"`
+	APIKeysFile string `json:"api_keys_file"` // hashed static API keys
+	JWKSFile    string `json:"jwks_file"`     // public keys for RS256 JWT bearer tokens
+	JWTIssuer   string `json:"jwt_issuer"`    // required "iss" claim when set
+	JWTAudience string `json:"jwt_audience"`  // required "aud" claim when set
+}
+
+// RateLimitConfig sets per-client rate limits on the API routes
+type RateLimitConfig struct {
+	Enabled           bool       `json:"enabled"`
+	TrustForwardedFor bool       `json:"trust_forwarded_for"` // identify anonymous clients by X-Forwarded-For; only behind a proxy that sets it
+	Addresses         RouteLimit `json:"addresses"`           // every API request per client IP, checked before authentication
+	CheckAvailability RouteLimit `json:"check_availability"`
+	Warehouses        RouteLimit `json:"warehouses"`
+}
+
+// CORSConfig lets browser scripts on other origins call the API. Lists are
+// comma-separated
+type CORSConfig struct {
+	AllowedOrigins string   `json:"allowed_origins"` // such as https://shop.example.com, or *; empty disables CORS
+	AllowedMethods string   `json:"allowed_methods"`
+	AllowedHeaders string   `json:"allowed_headers"`
+	MaxAge         Duration `json:"max_age"` // how long browsers may cache a preflight answer
+}
+
+// Duration is a time.Duration written as a string such as "5s" in config files
+type Duration time.Duration
+
+// String formats the duration like time.Duration
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Set parses a duration string such as "1m30s"
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\"")
+	}
+	return d.Set(value)
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		Env:         "development",
+		ListenAddr:  ":8080",
+		DataDir:     ".",
+		TenantsFile: "tenants.json",
+		Server: ServerConfig{
+			ReadTimeout:       Duration(10 * time.Second),
+			ReadHeaderTimeout: Duration(5 * time.Second),
+			WriteTimeout:      Duration(15 * time.Second),
+			IdleTimeout:       Duration(60 * time.Second),
+			ShutdownTimeout:   Duration(20 * time.Second),
+			RequestTimeout:    Duration(10 * time.Second),
+		},
+		Inventory: InventoryConfig{
+			Adapter:          AdapterAuto,
+			File:             "inventory.json",
+			Dir:              "inventory.d",
+			WatchInterval:    Duration(5 * time.Second),
+			APITimeout:       Duration(defaultAPITimeout),
+			CacheNegativeTTL: Duration(30 * time.Second),
+		},
+		Rules: RulesConfig{
+			ReserveRatio:      DefaultReserveRatio,
+			WeekendMultiplier: DefaultWeekendMultiplier,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: LogFormatJSON,
+		},
+		RateLimit: RateLimitConfig{
+			Addresses:         RouteLimit{Rate: 50, Burst: 100},
+			CheckAvailability: RouteLimit{Rate: 10, Burst: 20},
+			Warehouses:        RouteLimit{Rate: 5, Burst: 10},
+		},
+		CORS: CORSConfig{
+			AllowedMethods: "GET, POST",
+			AllowedHeaders: "Authorization, Content-Type, X-API-Key, X-Tenant-ID, X-Request-ID, traceparent",
+			MaxAge:         Duration(10 * time.Minute),
+		},
+		Tracing: TracingConfig{
+			ServiceName:    "product-availability-api",
+			ExportInterval: Duration(5 * time.Second),
+		},
+	}
+}
+
+// setting is a config field that can be overridden by an environment
+// variable and a command-line flag
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value // bound to the Config field
+}
+
+// settings lists every overridable field of c
+func (c *Config) settings() []setting {
+	return []setting{
+		{"env", "GO_ENV", "deployment environment", (*stringValue)(&c.Env)},
+		{"listen-addr", "AVAILABILITY_LISTEN_ADDR", "address the HTTP server listens on", (*stringValue)(&c.ListenAddr)},
+		{"data-dir", "AVAILABILITY_DATA_DIR", "directory holding inventory and rule files", (*stringValue)(&c.DataDir)},
+		{"tenants-file", "AVAILABILITY_TENANTS_FILE", "tenant definitions; multi-tenant mode when the file exists", (*stringValue)(&c.TenantsFile)},
+		{"read-timeout", "AVAILABILITY_READ_TIMEOUT", "maximum time to read a request", &c.Server.ReadTimeout},
+		{"read-header-timeout", "AVAILABILITY_READ_HEADER_TIMEOUT", "maximum time to read request headers", &c.Server.ReadHeaderTimeout},
+		{"write-timeout", "AVAILABILITY_WRITE_TIMEOUT", "maximum time to write a response", &c.Server.WriteTimeout},
+		{"idle-timeout", "AVAILABILITY_IDLE_TIMEOUT", "how long idle keep-alive connections stay open", &c.Server.IdleTimeout},
+		{"shutdown-timeout", "AVAILABILITY_SHUTDOWN_TIMEOUT", "how long in-flight requests may drain on shutdown", &c.Server.ShutdownTimeout},
+		{"request-timeout", "AVAILABILITY_REQUEST_TIMEOUT", "deadline for answering an API request", &c.Server.RequestTimeout},
+		{"inventory-adapter", "AVAILABILITY_INVENTORY_ADAPTER", "inventory adapter: auto, file, directory or api", (*stringValue)(&c.Inventory.Adapter)},
+		{"inventory-file", "AVAILABILITY_INVENTORY_FILE", "inventory file for the file adapter", (*stringValue)(&c.Inventory.File)},
+		{"inventory-dir", "AVAILABILITY_INVENTORY_DIR", "inventory directory for the directory adapter", (*stringValue)(&c.Inventory.Dir)},
+		{"inventory-watch-interval", "AVAILABILITY_INVENTORY_WATCH_INTERVAL", "reload interval for the directory adapter", &c.Inventory.WatchInterval},
+		{"inventory-api-url", "AVAILABILITY_INVENTORY_API_URL", "base URL for the api adapter", (*stringValue)(&c.Inventory.APIURL)},
+		{"inventory-api-timeout", "AVAILABILITY_INVENTORY_API_TIMEOUT", "timeout for each inventory API call", &c.Inventory.APITimeout},
+		{"inventory-fallback-file", "AVAILABILITY_INVENTORY_FALLBACK_FILE", "inventory snapshot the api adapter falls back to", (*stringValue)(&c.Inventory.FallbackFile)},
+		{"inventory-cache-ttl", "AVAILABILITY_INVENTORY_CACHE_TTL", "cache stock levels this long; 0 disables the cache", &c.Inventory.CacheTTL},
+		{"inventory-cache-negative-ttl", "AVAILABILITY_INVENTORY_CACHE_NEGATIVE_TTL", "cache \"not found\" answers this long", &c.Inventory.CacheNegativeTTL},
+		{"inventory-cache-max-entries", "AVAILABILITY_INVENTORY_CACHE_MAX_ENTRIES", "maximum cached entries; 0 means unbounded", (*intValue)(&c.Inventory.CacheMaxEntries)},
+		{"inventory-max-age", "AVAILABILITY_INVENTORY_MAX_AGE", "report inventory data older than this as stale; 0 disables", &c.Inventory.MaxAge},
+		{"reserve-ratio", "AVAILABILITY_RESERVE_RATIO", "share of stock always kept in reserve", (*floatValue)(&c.Rules.ReserveRatio)},
+		{"weekend-multiplier", "AVAILABILITY_WEEKEND_MULTIPLIER", "stock needed per unit ordered on weekends", (*intValue)(&c.Rules.WeekendMultiplier)},
+		{"min-shelf-life-days", "AVAILABILITY_MIN_SHELF_LIFE_DAYS", "days lots must remain good after shipping", (*intValue)(&c.Rules.MinShelfLifeDays)},
+		{"log-level", "AVAILABILITY_LOG_LEVEL", "log level: debug, info, warn or error", (*stringValue)(&c.Log.Level)},
+		{"log-format", "AVAILABILITY_LOG_FORMAT", "log format: json or text", (*stringValue)(&c.Log.Format)},
+		{"tracing-endpoint", "AVAILABILITY_TRACING_ENDPOINT", "OTLP/HTTP traces URL; empty disables tracing", (*stringValue)(&c.Tracing.Endpoint)},
+		{"tracing-service-name", "AVAILABILITY_TRACING_SERVICE_NAME", "service name reported with traces", (*stringValue)(&c.Tracing.ServiceName)},
+		{"tracing-export-interval", "AVAILABILITY_TRACING_EXPORT_INTERVAL", "how often finished spans are sent", &c.Tracing.ExportInterval},
+		{"auth-enabled", "AVAILABILITY_AUTH_ENABLED", "require credentials on the API and metrics endpoints", (*boolValue)(&c.Auth.Enabled)},
+		{"auth-api-keys-file", "AVAILABILITY_AUTH_API_KEYS_FILE", "hashed API keys, relative to the data directory", (*stringValue)(&c.Auth.APIKeysFile)},
+		{"auth-jwks-file", "AVAILABILITY_AUTH_JWKS_FILE", "JWKS file for JWT bearer tokens, relative to the data directory", (*stringValue)(&c.Auth.JWKSFile)},
+		{"auth-jwt-issuer", "AVAILABILITY_AUTH_JWT_ISSUER", "required JWT issuer", (*stringValue)(&c.Auth.JWTIssuer)},
+		{"auth-jwt-audience", "AVAILABILITY_AUTH_JWT_AUDIENCE", "required JWT audience", (*stringValue)(&c.Auth.JWTAudience)},
+		{"rate-limit-enabled", "AVAILABILITY_RATE_LIMIT_ENABLED", "limit how often each client may call the API", (*boolValue)(&c.RateLimit.Enabled)},
+		{"rate-limit-trust-forwarded-for", "AVAILABILITY_RATE_LIMIT_TRUST_FORWARDED_FOR", "identify anonymous clients by X-Forwarded-For", (*boolValue)(&c.RateLimit.TrustForwardedFor)},
+		{"rate-limit-addresses-rate", "AVAILABILITY_RATE_LIMIT_ADDRESSES_RATE", "API requests per second per client IP, before authentication; 0 is unlimited", (*floatValue)(&c.RateLimit.Addresses.Rate)},
+		{"rate-limit-addresses-burst", "AVAILABILITY_RATE_LIMIT_ADDRESSES_BURST", "API requests a client IP may make at once", (*intValue)(&c.RateLimit.Addresses.Burst)},
+		{"rate-limit-check-availability-rate", "AVAILABILITY_RATE_LIMIT_CHECK_AVAILABILITY_RATE", "availability checks per second per client; 0 is unlimited", (*floatValue)(&c.RateLimit.CheckAvailability.Rate)},
+		{"rate-limit-check-availability-burst", "AVAILABILITY_RATE_LIMIT_CHECK_AVAILABILITY_BURST", "availability checks a client may make at once", (*intValue)(&c.RateLimit.CheckAvailability.Burst)},
+		{"rate-limit-warehouses-rate", "AVAILABILITY_RATE_LIMIT_WAREHOUSES_RATE", "warehouse listings per second per client; 0 is unlimited", (*floatValue)(&c.RateLimit.Warehouses.Rate)},
+		{"rate-limit-warehouses-burst", "AVAILABILITY_RATE_LIMIT_WAREHOUSES_BURST", "warehouse listings a client may make at once", (*intValue)(&c.RateLimit.Warehouses.Burst)},
+		{"cors-allowed-origins", "AVAILABILITY_CORS_ALLOWED_ORIGINS", "comma-separated origins browsers may call the API from, or *; empty disables CORS", (*stringValue)(&c.CORS.AllowedOrigins)},
+		{"cors-allowed-methods", "AVAILABILITY_CORS_ALLOWED_METHODS", "comma-separated methods allowed in cross-origin calls", (*stringValue)(&c.CORS.AllowedMethods)},
+		{"cors-allowed-headers", "AVAILABILITY_CORS_ALLOWED_HEADERS", "comma-separated request headers allowed in cross-origin calls", (*stringValue)(&c.CORS.AllowedHeaders)},
+		{"cors-max-age", "AVAILABILITY_CORS_MAX_AGE", "how long browsers may cache a preflight answer", &c.CORS.MaxAge},
+	}
+}
+
+// LoadConfig builds the effective configuration from defaults, the config
+// file named by --config (or AVAILABILITY_CONFIG), environment variables and flags.
+// printConfig reports whether --print-config was given
+func LoadConfig(args []string, getenv func(string) string) (cfg Config, printConfig bool, err error) {
+	cfg = DefaultConfig()
+
+	// Flags are recorded first and applied last, so they win over the file
+	// and the environment
+	fs := flag.NewFlagSet("availability", flag.ContinueOnError)
+	configFile := fs.String("config", getenv("AVAILABILITY_CONFIG"), "JSON or YAML config file")
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective configuration and exit")
+	type override struct{ flag, value string }
+	var overrides []override
+	for _, s := range cfg.settings() {
+		name := s.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s, default %s)", s.usage, s.env, s.value), func(value string) error {
+			overrides = append(overrides, override{name, value})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, false, err
+	}
+
+	if *configFile != "" {
+		if err := loadConfigFile(&cfg, *configFile); err != nil {
+			return cfg, false, err
+		}
+	}
+
+	settings := map[string]setting{}
+	for _, s := range cfg.settings() {
+		settings[s.flag] = s
+		if value := getenv(s.env); value != "" {
+			if err := s.value.Set(value); err != nil {
+				return cfg, false, fmt.Errorf("invalid %s %q: %w", s.env, value, err)
+			}
+		}
+	}
+	for _, o := range overrides {
+		if err := settings[o.flag].value.Set(o.value); err != nil {
+			return cfg, false, fmt.Errorf("invalid -%s %q: %w", o.flag, o.value, err)
+		}
+	}
+
+	return cfg, printConfig, cfg.validate()
+}
+
+// loadConfigFile merges a JSON or YAML (.yaml, .yml) file into cfg. Keys the
+// file leaves out keep their current values; unknown keys are rejected
+func loadConfigFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		values, err := parseYAML(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		data, err = json.Marshal(values)
+		if err != nil {
+			return fmt.Errorf("failed to convert config file %s: %w", path, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// validate checks the merged configuration
+func (c Config) validate() error {
+	switch c.Inventory.Adapter {
+	case AdapterAuto, AdapterFile, AdapterDirectory:
+	case AdapterAPI:
+		if c.Inventory.APIURL == "" {
+			return fmt.Errorf("inventory adapter %q needs an API URL", AdapterAPI)
+		}
+	default:
+		return fmt.Errorf("unknown inventory adapter %q (want auto, file, directory or api)", c.Inventory.Adapter)
+	}
+	if c.Inventory.FallbackFile != "" && c.Inventory.Adapter != AdapterAPI {
+		return fmt.Errorf("inventory fallback file needs the %q adapter", AdapterAPI)
+	}
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen address must not be empty")
+	}
+	if c.Rules.ReserveRatio < 0 || c.Rules.ReserveRatio >= 1 {
+		return fmt.Errorf("reserve ratio must be at least 0 and below 1, got %v", c.Rules.ReserveRatio)
+	}
+	if c.Rules.WeekendMultiplier < 1 {
+		return fmt.Errorf("weekend multiplier must be at least 1, got %d", c.Rules.WeekendMultiplier)
+	}
+	if c.Rules.MinShelfLifeDays < 0 {
+		return fmt.Errorf("minimum shelf life must not be negative, got %d", c.Rules.MinShelfLifeDays)
+	}
+	server := c.Server
+	if server.ReadTimeout <= 0 || server.ReadHeaderTimeout <= 0 || server.WriteTimeout <= 0 || server.IdleTimeout <= 0 || server.ShutdownTimeout <= 0 || server.RequestTimeout <= 0 {
+		return fmt.Errorf("server timeouts must be positive")
+	}
+	if server.RequestTimeout >= server.WriteTimeout {
+		return fmt.Errorf("request timeout (%s) must be shorter than the write timeout (%s), or timed out requests cannot be answered", server.RequestTimeout, server.WriteTimeout)
+	}
+	if c.Inventory.APITimeout <= 0 || c.Inventory.WatchInterval <= 0 {
+		return fmt.Errorf("inventory API timeout and watch interval must be positive")
+	}
+	if c.Inventory.MaxAge < 0 {
+		return fmt.Errorf("inventory max age must not be negative")
+	}
+	if _, err := newLogger(io.Discard, c.Log); err != nil {
+		return err
+	}
+	if c.Tracing.Endpoint != "" {
+		u, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid tracing endpoint %q (want an http or https URL)", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.ServiceName == "" || c.Tracing.ExportInterval <= 0 {
+		return fmt.Errorf("tracing service name must not be empty and export interval must be positive")
+	}
+	for name, limit := range map[string]RouteLimit{"addresses": c.RateLimit.Addresses, "check_availability": c.RateLimit.CheckAvailability, "warehouses": c.RateLimit.Warehouses} {
+		if limit.Rate < 0 || (limit.Rate > 0 && limit.Burst < 1) {
+			return fmt.Errorf("rate limit %s: rate must not be negative and burst must be at least 1", name)
+		}
+	}
+	if c.Auth.Enabled && c.Auth.APIKeysFile == "" && c.Auth.JWKSFile == "" {
+		return fmt.Errorf("authentication needs an API keys file or a JWKS file")
+	}
+	for _, origin := range splitList(c.CORS.AllowedOrigins) {
+		if err := validateOrigin(origin); err != nil {
+			return err
+		}
+	}
+	if c.CORS.AllowedOrigins != "" && len(splitList(c.CORS.AllowedMethods)) == 0 {
+		return fmt.Errorf("CORS needs at least one allowed method")
+	}
+	if c.CORS.MaxAge < 0 {
+		return fmt.Errorf("CORS max age must not be negative")
+	}
+	return nil
+}
+
+// ServiceOptions returns the service options for the configured rule parameters
+func (c Config) ServiceOptions() []ServiceOption {
+	return []ServiceOption{
+		WithReserveRatio(c.Rules.ReserveRatio),
+		WithWeekendMultiplier(c.Rules.WeekendMultiplier),
+		WithMinShelfLife(c.Rules.MinShelfLifeDays),
+	}
+}
+
+// stringValue, intValue, floatValue and boolValue adapt config fields to flag.Value
+type (
+	stringValue string
+	intValue    int
+	floatValue  float64
+	boolValue   bool
+)
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(value string) error {
+	*v = stringValue(value)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(value string) error {
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	*v = intValue(parsed)
+	return nil
+}
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+func (v *floatValue) Set(value string) error {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(parsed)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) Set(value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(parsed)
+	return nil
+}
+
+// parseYAML parses the subset of YAML config files need: nested mappings of
+// scalars, indented with spaces, with # comments. Scalars that look like
+// numbers or booleans become numbers or booleans; everything else, and any
+// quoted value, is a string
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	type frame struct {
+		indent int
+		values map[string]interface{}
+	}
+	root := map[string]interface{}{}
+	stack := []frame{{indent: -1, values: root}}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		content := strings.TrimRight(stripYAMLComment(line), " \t\r")
+		if strings.TrimSpace(content) == "" || strings.TrimSpace(content) == "---" {
+			continue
+		}
+		if strings.Contains(content, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+
+		indent := len(content) - len(strings.TrimLeft(content, " "))
+		key, value, ok := strings.Cut(strings.TrimSpace(content), ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+		}
+		if strings.HasPrefix(key, "- ") {
+			return nil, fmt.Errorf("line %d: lists are not supported", lineNo)
+		}
+
+		for indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].values
+		if _, dup := parent[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			child := map[string]interface{}{}
+			parent[key] = child
+			stack = append(stack, frame{indent: indent, values: child})
+			continue
+		}
+		parent[key] = parseYAMLScalar(value)
+	}
+	return root, nil
+}
+
+// stripYAMLComment removes a # comment that is not inside quotes
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseYAMLScalar converts a scalar to a string, number or boolean
+func parseYAMLScalar(value string) interface{} {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return value
+}
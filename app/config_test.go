@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// envFrom returns a getenv function backed by a map
+func envFrom(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	cfg, printConfig, err := LoadConfig(nil, envFrom(nil))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if printConfig {
+		t.Error("Expected print-config to be off by default")
+	}
+	if cfg.ListenAddr != ":8080" || cfg.Inventory.Adapter != AdapterAuto || cfg.Rules.ReserveRatio != 0.10 || cfg.Rules.WeekendMultiplier != 2 {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadConfig_LayersOverrideInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"listen_addr": ":9000", "env": "staging", "inventory": {"api_timeout": "5s"}, "rules": {"reserve_ratio": 0.2}}`), 0o644)
+
+	env := map[string]string{
+		"AVAILABILITY_CONFIG":      path,
+		"AVAILABILITY_LISTEN_ADDR": ":9100",
+		"GO_ENV":                   "production",
+	}
+	cfg, printConfig, err := LoadConfig([]string{"--listen-addr", ":9200", "--print-config"}, envFrom(env))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.ListenAddr != ":9200" {
+		t.Errorf("Expected flag to win, got listen_addr=%s", cfg.ListenAddr)
+	}
+	if cfg.Env != "production" {
+		t.Errorf("Expected GO_ENV to override the file, got env=%s", cfg.Env)
+	}
+	if cfg.Rules.ReserveRatio != 0.2 || time.Duration(cfg.Inventory.APITimeout) != 5*time.Second {
+		t.Errorf("Expected file values to override defaults, got %+v", cfg)
+	}
+	if cfg.Inventory.File != "inventory.json" {
+		t.Errorf("Expected keys missing from the file to keep defaults, got file=%s", cfg.Inventory.File)
+	}
+	if !printConfig {
+		t.Error("Expected --print-config to be reported")
+	}
+}
+
+func TestLoadConfig_ExampleYAML(t *testing.T) {
+	cfg, _, err := LoadConfig([]string{"--config", "config.example.yaml"}, envFrom(nil))
+	if err != nil {
+		t.Fatalf("Failed to load example config: %v", err)
+	}
+	if cfg != DefaultConfig() {
+		t.Errorf("Expected example config to match the defaults, got %+v", cfg)
+	}
+}
+
+func TestLoadConfig_APIWithFallbackFile(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer api.Close()
+
+	cfg, _, err := LoadConfig(nil, envFrom(map[string]string{
+		"AVAILABILITY_INVENTORY_ADAPTER":       "api",
+		"AVAILABILITY_INVENTORY_API_URL":       api.URL,
+		"AVAILABILITY_INVENTORY_FALLBACK_FILE": "inventory.json",
+	}))
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	dataset, err := LoadDataset(".", cfg.Inventory)
+	if err != nil {
+		t.Fatalf("Failed to load dataset: %v", err)
+	}
+	t.Cleanup(dataset.Close)
+
+	info, err := lookupStock(t.Context(), dataset.Inventory, "PROD-123", "DE-Berlin")
+	if err != nil {
+		t.Fatalf("Expected the snapshot to answer while the API is down, got %v", err)
+	}
+	if info.StockLevel != 100 || info.Source != "snapshot" {
+		t.Errorf("Expected stock 100 from the snapshot, got %+v", info)
+	}
+}
+
+func TestLoadConfig_RejectsInvalidSettings(t *testing.T) {
+	dir := t.TempDir()
+	unknownKey := filepath.Join(dir, "unknown.yaml")
+	os.WriteFile(unknownKey, []byte("inventory:\n  adaptor: file\n"), 0o644)
+
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+	}{
+		{"unknown adapter", []string{"--inventory-adapter", "database"}, nil},
+		{"api adapter without URL", []string{"--inventory-adapter", "api"}, nil},
+		{"fallback file without the api adapter", []string{"--inventory-fallback-file", "inventory.json"}, nil},
+		{"weekend multiplier below 1", nil, map[string]string{"AVAILABILITY_WEEKEND_MULTIPLIER": "0"}},
+		{"malformed duration", nil, map[string]string{"AVAILABILITY_INVENTORY_API_TIMEOUT": "soon"}},
+		{"unknown key in file", []string{"--config", unknownKey}, nil},
+		{"request timeout not below write timeout", []string{"--request-timeout", "15s"}, nil},
+		{"tracing endpoint without scheme", []string{"--tracing-endpoint", "collector:4318"}, nil},
+		{"auth without credentials", []string{"--auth-enabled", "true"}, nil},
+		{"malformed boolean", nil, map[string]string{"AVAILABILITY_AUTH_ENABLED": "sometimes"}},
+		{"rate limit without burst", []string{"--rate-limit-warehouses-burst", "0"}, nil},
+		{"CORS origin with a path", []string{"--cors-allowed-origins", "https://shop.example.com/"}, nil},
+		{"CORS origin without a scheme", nil, map[string]string{"AVAILABILITY_CORS_ALLOWED_ORIGINS": "https://shop.example.com, shop.example.org"}},
+		{"CORS without methods", []string{"--cors-allowed-origins", "*", "--cors-allowed-methods", " , "}, nil},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := LoadConfig(tt.args, envFrom(tt.env)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestCheckAvailability_ConfiguredRuleParameters(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Rules.ReserveRatio = 0.25
+	service := NewAvailabilityService(NewMockInventoryAdapter(), cfg.ServiceOptions()...)
+
+	// PROD-123 has 100 units in DE-Berlin; a 25% reserve leaves 75
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+	if resp.AvailableQuantity != 75 {
+		t.Errorf("Expected 75 available with a 25%% reserve, got %d", resp.AvailableQuantity)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsExposedHeaders are the response headers browser scripts may read
+// besides the CORS-safelisted ones
+var corsExposedHeaders = []string{
+	"X-Request-ID",
+	"RateLimit-Limit",
+	"RateLimit-Remaining",
+	"RateLimit-Reset",
+	"Retry-After",
+	"WWW-Authenticate",
+}
+
+// CORS lets browser scripts on the allowed origins call the API. It answers
+// preflight requests itself and adds the CORS headers to every other
+// response. A nil *CORS allows no cross-origin calls
+type CORS struct {
+	anyOrigin bool
+	origins   []string
+	methods   []string
+	headers   string // Access-Control-Allow-Headers
+	maxAge    string // Access-Control-Max-Age; empty leaves caching to the browser
+}
+
+// NewCORS creates the CORS handler for the configuration, or returns nil
+// when no origins are allowed
+func NewCORS(cfg CORSConfig) *CORS {
+	origins := splitList(cfg.AllowedOrigins)
+	if len(origins) == 0 {
+		return nil
+	}
+	c := &CORS{
+		anyOrigin: slices.Contains(origins, "*"),
+		origins:   origins,
+		methods:   splitList(cfg.AllowedMethods),
+		headers:   strings.Join(splitList(cfg.AllowedHeaders), ", "),
+	}
+	if maxAge := time.Duration(cfg.MaxAge); maxAge > 0 {
+		c.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	}
+	return c
+}
+
+// Handler answers preflight requests and adds CORS headers to responses
+// from next. It belongs outside authentication, as browsers send preflights
+// without credentials and only let scripts read a 401 that carries them
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	if c == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		requestMethod := r.Header.Get("Access-Control-Request-Method")
+		if r.Method == http.MethodOptions && origin != "" && requestMethod != "" {
+			c.preflight(w, origin, requestMethod)
+			return
+		}
+
+		if !c.anyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+		if origin != "" && c.allowOrigin(w, origin) {
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers a preflight request. A request from an origin or for a
+// method that is not allowed gets no CORS headers, so the browser blocks
+// the call that would have followed
+func (c *CORS) preflight(w http.ResponseWriter, origin, method string) {
+	w.Header().Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	if slices.Contains(c.methods, method) && c.allowOrigin(w, origin) {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+		if c.headers != "" {
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+		}
+		if c.maxAge != "" {
+			w.Header().Set("Access-Control-Max-Age", c.maxAge)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowOrigin sets Access-Control-Allow-Origin if origin is allowed and
+// reports whether it is
+func (c *CORS) allowOrigin(w http.ResponseWriter, origin string) bool {
+	switch {
+	case c.anyOrigin:
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	case slices.Contains(c.origins, origin):
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	default:
+		return false
+	}
+	return true
+}
+
+// validateOrigin checks that origin is "*" or a bare scheme://host[:port],
+// the form browsers send in the Origin header
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("CORS origin %q must be * or scheme://host[:port]", origin)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated setting, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
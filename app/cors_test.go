@@ -0,0 +1,150 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestCORS returns CORS for origins with the default methods and headers
+func newTestCORS(origins string) *CORS {
+	cfg := DefaultConfig().CORS
+	cfg.AllowedOrigins = origins
+	return NewCORS(cfg)
+}
+
+// corsRequest serves one request through the CORS handler in front of a
+// handler that only accepts POST, like HandleCheckAvailability
+func corsRequest(cors *CORS, method string, header map[string]string) *httptest.ResponseRecorder {
+	handler := cors.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))
+	req := httptest.NewRequest(method, "/api/check-availability", nil)
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	tests := []struct {
+		name       string
+		origins    string
+		origin     string
+		method     string
+		wantOrigin string
+	}{
+		{"allowed origin", "https://shop.example.com, https://admin.example.com", "https://admin.example.com", http.MethodPost, "https://admin.example.com"},
+		{"any origin", "*", "https://shop.example.com", http.MethodPost, "*"},
+		{"other origin", "https://shop.example.com", "https://evil.example.com", http.MethodPost, ""},
+		{"origin differing in port", "https://shop.example.com", "https://shop.example.com:8443", http.MethodPost, ""},
+		{"method not allowed", "https://shop.example.com", "https://shop.example.com", http.MethodDelete, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := corsRequest(newTestCORS(tt.origins), http.MethodOptions, map[string]string{
+				"Origin":                         tt.origin,
+				"Access-Control-Request-Method":  tt.method,
+				"Access-Control-Request-Headers": "content-type, authorization",
+			})
+
+			// Preflights never reach the handler, allowed or not
+			if rr.Code != http.StatusNoContent {
+				t.Fatalf("Expected status 204, got %d", rr.Code)
+			}
+			if got := rr.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Fatalf("Expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+			if tt.wantOrigin == "" {
+				if got := rr.Header().Get("Access-Control-Allow-Methods"); got != "" {
+					t.Errorf("Expected no allowed methods for a refused preflight, got %q", got)
+				}
+				return
+			}
+			wantHeaders := map[string]string{
+				"Access-Control-Allow-Methods": "GET, POST",
+				"Access-Control-Allow-Headers": "Authorization, Content-Type, X-API-Key, X-Tenant-ID, X-Request-ID, traceparent",
+				"Access-Control-Max-Age":       "600",
+			}
+			for key, want := range wantHeaders {
+				if got := rr.Header().Get(key); got != want {
+					t.Errorf("Expected %s %q, got %q", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	cors := newTestCORS("https://shop.example.com")
+
+	rr := corsRequest(cors, http.MethodPost, map[string]string{"Origin": "https://shop.example.com"})
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "https://shop.example.com" {
+		t.Errorf("Expected the origin to be allowed, got %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-Request-ID") || !strings.Contains(got, "Retry-After") {
+		t.Errorf("Expected the request ID and rate limit headers to be exposed, got %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %q", got)
+	}
+
+	rr = corsRequest(cors, http.MethodPost, map[string]string{"Origin": "https://evil.example.com"})
+	if rr.Code != http.StatusOK || rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected the request served without CORS headers, got %d with %q", rr.Code, rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// OPTIONS without Access-Control-Request-Method is not a preflight
+	rr = corsRequest(cors, http.MethodOptions, map[string]string{"Origin": "https://shop.example.com"})
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected a plain OPTIONS request to reach the handler, got %d", rr.Code)
+	}
+}
+
+func TestCORS_PreflightNeedsNoCredentials(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]APIKey{{Name: "storefront", SHA256: hashAPIKey("storefront-secret"), Scopes: []string{ScopeAvailabilityRead}}})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	cors := newTestCORS("https://shop.example.com")
+	handler := cors.Handler(NewAuth(keys).Require(ScopeAvailabilityRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/warehouses", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected the preflight answered without credentials, got %d", rr.Code)
+	}
+
+	// The browser only lets the script read the 401 if it carries CORS headers
+	req = httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+	req.Header.Set("Origin", "https://shop.example.com")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || rr.Header().Get("Access-Control-Allow-Origin") != "https://shop.example.com" {
+		t.Errorf("Expected a readable 401, got %d with %q", rr.Code, rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestCORS_DisabledAddsNothing(t *testing.T) {
+	cors := NewCORS(DefaultConfig().CORS)
+	if cors != nil {
+		t.Fatal("Expected CORS disabled without allowed origins")
+	}
+
+	rr := corsRequest(cors, http.MethodOptions, map[string]string{
+		"Origin":                        "https://shop.example.com",
+		"Access-Control-Request-Method": http.MethodPost,
+	})
+	if rr.Code != http.StatusMethodNotAllowed || rr.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("Expected the request passed through untouched, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Dataset is everything an availability service is built from: an inventory
+// source and the rule configuration, loaded from one data directory.
+// Nothing in a dataset is shared with another, which is what keeps tenants
+// apart
+type Dataset struct {
+	Dir             string
+	Inventory       InventoryAdapter
+	InventorySource string
+	Warehouses      *WarehouseRegistry
+	Service         *AvailabilityService
+	Monitor         *MonitoredInventoryAdapter // health and activity of the inventory source
+	Cache           *CachingInventoryAdapter   // nil when caching is off
+
+	stopWatch func()
+}
+
+// LoadDataset loads the inventory and rule files in dir and builds a service
+// over them, with the inventory adapter chosen by the inventory config.
+// Extra service options are applied after the loaded collaborators
+func LoadDataset(dir string, inventory InventoryConfig, options ...ServiceOption) (*Dataset, error) {
+	d := &Dataset{Dir: dir}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	var dirAdapter *DirectoryInventoryAdapter
+	switch inventory.Adapter {
+	case AdapterAPI:
+		apiAdapter := NewAPIInventoryAdapter(inventory.APIURL)
+		apiAdapter.client.Timeout = time.Duration(inventory.APITimeout)
+		d.Inventory = apiAdapter
+		d.InventorySource = inventory.APIURL
+		if inventory.FallbackFile != "" {
+			// The snapshot only answers while the API is unavailable
+			d.Inventory = NewCompositeInventoryAdapter(
+				InventorySource{Name: "api", Adapter: apiAdapter},
+				InventorySource{Name: "snapshot", Adapter: NewFileInventoryAdapter(path(inventory.FallbackFile))},
+			)
+			d.InventorySource = inventory.APIURL + ", falling back to " + path(inventory.FallbackFile)
+		}
+	case AdapterAuto, AdapterDirectory:
+		// With the auto adapter an inventory directory, where it exists, is
+		// merged and reloaded as teams update their exports
+		if info, err := os.Stat(path(inventory.Dir)); inventory.Adapter == AdapterDirectory || (err == nil && info.IsDir()) {
+			dirAdapter = NewDirectoryInventoryAdapter(path(inventory.Dir))
+			d.Inventory = dirAdapter
+			d.InventorySource = path(inventory.Dir) + "/"
+			break
+		}
+		fallthrough
+	default:
+		d.Inventory = NewFileInventoryAdapter(path(inventory.File))
+		d.InventorySource = path(inventory.File)
+	}
+	// The monitor sits below the cache so it sees every call that reaches the source
+	d.Monitor = NewMonitoredInventoryAdapter("inventory", d.Inventory, time.Duration(inventory.MaxAge))
+	d.Inventory = d.Monitor
+	if inventory.CacheTTL > 0 {
+		d.Cache = NewCachingInventoryAdapter(d.Inventory, CacheOptions{
+			TTL:         time.Duration(inventory.CacheTTL),
+			NegativeTTL: time.Duration(inventory.CacheNegativeTTL),
+			MaxEntries:  inventory.CacheMaxEntries,
+		})
+		d.Inventory = d.Cache
+	}
+	if err := d.Inventory.LoadInventory(); err != nil {
+		return nil, fmt.Errorf("failed to load inventory: %w", err)
+	}
+
+	d.Warehouses = NewWarehouseRegistry(path("warehouses.json"))
+	catalog := NewProductCatalog(path("products.json"))
+	inbound := NewInboundSchedule(path("inbound.json"))
+	replenishment := NewReplenishmentPlan(path("replenishment.json"))
+	bundles := NewBundleCatalog(path("bundles.json"))
+	substitutions := NewSubstitutionMap(path("substitutes.json"))
+	allocations := NewAllocationPlan(path("allocations.json"))
+
+	// Rule files are optional, so a data directory holding only inventory
+	// keeps working; a missing file turns its rules off
+	loaders := []struct {
+		name   string
+		load   func() error
+		option ServiceOption
+	}{
+		{"warehouses", d.Warehouses.Load, WithWarehouseRegistry(d.Warehouses)},
+		{"product catalog", catalog.Load, WithProductCatalog(catalog)},
+		{"inbound schedule", inbound.Load, WithInboundSchedule(inbound)},
+		{"replenishment plan", replenishment.Load, WithReplenishmentPlan(replenishment)},
+		{"bundles", bundles.Load, WithBundleCatalog(bundles)},
+		{"substitution map", substitutions.Load, WithSubstitutionMap(substitutions)},
+		{"allocation plan", allocations.Load, WithAllocationPlan(allocations)},
+	}
+	var ruleOptions []ServiceOption
+	for _, loader := range loaders {
+		err := loader.load()
+		if errors.Is(err, fs.ErrNotExist) {
+			slog.Info("no rule file, rules off", "rules", loader.name, "dir", dir)
+			if loader.name == "warehouses" {
+				// Without a registry any warehouse code is accepted, as before
+				d.Warehouses = nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", loader.name, err)
+		}
+		ruleOptions = append(ruleOptions, loader.option)
+	}
+
+	if err := bundles.checkCatalog(catalog); err != nil {
+		return nil, fmt.Errorf("failed to load bundles: %w", err)
+	}
+
+	d.Service = NewAvailabilityService(d.Inventory, append(ruleOptions, options...)...)
+
+	if dirAdapter != nil {
+		d.stopWatch = dirAdapter.Watch(time.Duration(inventory.WatchInterval), d.inventoryReloaded)
+	}
+	return d, nil
+}
+
+// inventoryReloaded records a reload by the directory watcher and drops the
+// cached stock levels it may have changed
+func (d *Dataset) inventoryReloaded(changed []string, err error) {
+	d.Monitor.RecordReload(changed, err)
+	if len(changed) > 0 && d.Cache != nil {
+		d.Cache.Purge()
+	}
+}
+
+// Close stops watching the dataset's inventory for changes
+func (d *Dataset) Close() {
+	if d.stopWatch != nil {
+		d.stopWatch()
+	}
+}
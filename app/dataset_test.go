@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadDataset_OnlyInventoryFile(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("inventory.json")
+	if err != nil {
+		t.Fatalf("Failed to read inventory.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "inventory.json"), data, 0o644); err != nil {
+		t.Fatalf("Failed to write inventory.json: %v", err)
+	}
+
+	dataset, err := LoadDataset(dir, DefaultConfig().Inventory)
+	if err != nil {
+		t.Fatalf("Expected a directory with only inventory.json to load, got %v", err)
+	}
+	t.Cleanup(dataset.Close)
+	if dataset.Warehouses != nil {
+		t.Error("Expected no warehouse registry without warehouses.json")
+	}
+
+	handler := NewAvailabilityHandler(dataset.Service, dataset.Warehouses)
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(`{"product_id": "PROD-123", "quantity": 5, "warehouse_location": "DE-Berlin", "channel": "web"}`))
+	rec := httptest.NewRecorder()
+	handler.HandleCheckAvailability(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"available": true`) {
+		t.Errorf("Expected PROD-123 available without rule files, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLoadDataset_MalformedRuleFileFails(t *testing.T) {
+	root := t.TempDir()
+	writeTenantDir(t, root, "retail", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 10}]`)
+	os.WriteFile(filepath.Join(root, "retail", "bundles.json"), []byte(`{"not": "a list"`), 0o644)
+
+	if _, err := LoadDataset(filepath.Join(root, "retail"), DefaultConfig().Inventory); err == nil {
+		t.Error("Expected a malformed rule file to fail the load")
+	}
+}
@@ -3,19 +3,23 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
 // AvailabilityHandler handles HTTP requests for the availability check endpoint
 type AvailabilityHandler struct {
 	availabilityService *AvailabilityService
+	warehouses          *WarehouseRegistry
 }
 
-// NewAvailabilityHandler creates a new handler with the given availability service
-func NewAvailabilityHandler(service *AvailabilityService) *AvailabilityHandler {
+// NewAvailabilityHandler creates a new handler with the given availability service.
+// When warehouses is non-nil, requests for unknown or inactive warehouses are rejected
+func NewAvailabilityHandler(service *AvailabilityService, warehouses *WarehouseRegistry) *AvailabilityHandler {
 	return &AvailabilityHandler{
 		availabilityService: service,
+		warehouses:          warehouses,
 	}
 }
 
@@ -49,17 +53,105 @@ func (h *AvailabilityHandler) HandleCheckAvailability(w http.ResponseWriter, r *
 		http.Error(w, "warehouse_location is required", http.StatusBadRequest)
 		return
 	}
+	if req.ShipDate != "" {
+		shipDate, err := time.Parse(time.DateOnly, req.ShipDate)
+		if err != nil {
+			http.Error(w, "ship_date must be a date in YYYY-MM-DD format", http.StatusBadRequest)
+			return
+		}
+		// ATP counts from the warehouse's own day, so validate against it
+		if shipDate.Before(h.availabilityService.warehouseToday(req.WarehouseLocation)) {
+			http.Error(w, "ship_date must not be in the past", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Channel != "" && !isKnownChannel(req.Channel) {
+		http.Error(w, "channel must be one of web, marketplace, b2b", http.StatusBadRequest)
+		return
+	}
+	if msg := h.validateWarehouse(req.WarehouseLocation); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	// Check availability using the service
-	response := h.availabilityService.CheckAvailability(req)
+	req.TenantID = tenantFromContext(r.Context())
+	response := h.availabilityService.CheckAvailability(r.Context(), req)
+	addLogAttrs(r.Context(),
+		slog.String("product_id", req.ProductID),
+		slog.String("warehouse", req.WarehouseLocation),
+		slog.String("outcome", response.Status),
+		slog.String("reason_code", response.ReasonCode))
 
 	// Send JSON response
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusForReason(response.ReasonCode))
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	err = encoder.Encode(response)
 	if err != nil {
-		log.Printf("Error encoding response: %v", err)
+		slog.ErrorContext(r.Context(), "encoding response", "error", err)
 	}
-}
\ No newline at end of file
+}
+
+// HandleListWarehouses handles GET /api/warehouses requests
+func (h *AvailabilityHandler) HandleListWarehouses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	warehouses := []Warehouse{}
+	if h.warehouses != nil {
+		warehouses = h.warehouses.List()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(map[string][]Warehouse{"warehouses": warehouses})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "encoding response", "error", err)
+	}
+}
+
+// validateWarehouse checks a warehouse code against the registry and returns
+// an error message, or "" if the code is acceptable
+func (h *AvailabilityHandler) validateWarehouse(code string) string {
+	if h.warehouses == nil {
+		return ""
+	}
+
+	warehouse, ok := h.warehouses.Lookup(code)
+	if !ok {
+		msg := fmt.Sprintf("warehouse_location %q is not a known warehouse", code)
+		if suggestion := h.warehouses.Suggest(code); suggestion != "" {
+			msg += fmt.Sprintf("; did you mean %q?", suggestion)
+		}
+		return msg
+	}
+	if !warehouse.Active {
+		return fmt.Sprintf("warehouse_location %q is not active", code)
+	}
+	return ""
+}
+
+// statusForReason maps a reason code to the HTTP status of the response.
+// Stock outcomes are 200; lookups that could not be answered are 404 or 5xx
+func statusForReason(code string) int {
+	switch code {
+	case ReasonUnknownUnit:
+		return http.StatusBadRequest
+	case ReasonTenantMismatch:
+		return http.StatusForbidden
+	case ReasonProductNotFound, ReasonUnknownWarehouse, ReasonUnknownProduct:
+		return http.StatusNotFound
+	case ReasonSourceUnavailable:
+		return http.StatusServiceUnavailable
+	case ReasonSourceTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusOK
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ErrorInventoryAdapter is a test double that fails every lookup with err
+type ErrorInventoryAdapter struct {
+	err error
+}
+
+func (e *ErrorInventoryAdapter) LoadInventory() error {
+	return nil
+}
+
+func (e *ErrorInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	return 0, e.err
+}
+
+// postAvailability sends a check-availability request to a handler backed by adapter
+func postAvailability(t *testing.T, adapter InventoryAdapter, body string) (*httptest.ResponseRecorder, Response) {
+	t.Helper()
+	handler := NewAvailabilityHandler(NewAvailabilityService(adapter), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.HandleCheckAvailability(rec, req)
+
+	var resp Response
+	if rec.Header().Get("Content-Type") == "application/json" {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid JSON response: %v", err)
+		}
+	}
+	return rec, resp
+}
+
+func TestHandleCheckAvailability_StatusByReason(t *testing.T) {
+	tests := []struct {
+		name       string
+		adapter    InventoryAdapter
+		productID  string
+		wantStatus int
+		wantCode   string
+	}{
+		{"available", NewMockInventoryAdapter(), "PROD-123", http.StatusOK, ReasonSufficientStock},
+		{"out of stock", NewMockInventoryAdapter(), "PROD-789", http.StatusOK, ReasonOutOfStock},
+		{"not found", NewMockInventoryAdapter(), "PROD-999", http.StatusNotFound, ReasonProductNotFound},
+		{"unknown warehouse", &ErrorInventoryAdapter{fmt.Errorf("%w: DE-berlin", ErrUnknownWarehouse)}, "PROD-123", http.StatusNotFound, ReasonUnknownWarehouse},
+		{"unavailable", &ErrorInventoryAdapter{fmt.Errorf("%w: connection refused", ErrSourceUnavailable)}, "PROD-123", http.StatusServiceUnavailable, ReasonSourceUnavailable},
+		{"unclassified error", &ErrorInventoryAdapter{fmt.Errorf("disk on fire")}, "PROD-123", http.StatusServiceUnavailable, ReasonSourceUnavailable},
+		{"timeout", &ErrorInventoryAdapter{ErrTimeout}, "PROD-123", http.StatusGatewayTimeout, ReasonSourceTimeout},
+	}
+
+	for _, tt := range tests {
+		body := fmt.Sprintf(`{"product_id": %q, "quantity": 1, "warehouse_location": "DE-Berlin"}`, tt.productID)
+		rec, resp := postAvailability(t, tt.adapter, body)
+
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.wantStatus, rec.Code)
+		}
+		if resp.ReasonCode != tt.wantCode {
+			t.Errorf("%s: expected reason_code=%s, got %s", tt.name, tt.wantCode, resp.ReasonCode)
+		}
+	}
+}
+
+func TestHandleCheckAvailability_RejectsInvalidInput(t *testing.T) {
+	rec, _ := postAvailability(t, NewMockInventoryAdapter(), `{"product_id": "PROD-123", "quantity": 0, "warehouse_location": "DE-Berlin"}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+}
+
+// BlockingInventoryAdapter is a test double whose lookups only end when the
+// context is done
+type BlockingInventoryAdapter struct{}
+
+func (b *BlockingInventoryAdapter) LoadInventory() error {
+	return nil
+}
+
+func (b *BlockingInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	<-ctx.Done()
+	return 0, contextError(ctx.Err())
+}
+
+func TestHandleCheckAvailability_RequestDeadline(t *testing.T) {
+	service := NewAvailabilityService(&BlockingInventoryAdapter{})
+	handler := withRequestTimeout(20*time.Millisecond, http.HandlerFunc(NewAvailabilityHandler(service, nil).HandleCheckAvailability))
+
+	body := `{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "DE-Berlin"}`
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body)))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("Expected status 504, got %d", rec.Code)
+	}
+	var resp Response
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.ReasonCode != ReasonSourceTimeout {
+		t.Errorf("Expected reason %s, got %s", ReasonSourceTimeout, resp.ReasonCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to end at its deadline, took %s", elapsed)
+	}
+}
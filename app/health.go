@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health check statuses
+const (
+	CheckOK       = "ok"
+	CheckDegraded = "degraded"
+	CheckFailing  = "failing"
+)
+
+// Readiness statuses reported by /readyz
+const (
+	ReadinessReady    = "ready"
+	ReadinessDegraded = "degraded"
+	ReadinessNotReady = "not_ready"
+)
+
+// HealthCheck is the result of one dependency check
+type HealthCheck struct {
+	Name    string     `json:"name"`
+	Status  string     `json:"status"` // ok, degraded or failing
+	Message string     `json:"message,omitempty"`
+	AsOf    *time.Time `json:"as_of,omitempty"` // when the dependency's data was produced
+}
+
+// Readiness is the /readyz response body
+type Readiness struct {
+	Status string        `json:"status"` // ready, degraded or not_ready
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthHandler serves the liveness and readiness probes. Until SetChecks is
+// called the service is still starting and reported as not ready
+type HealthHandler struct {
+	mu         sync.RWMutex
+	started    bool
+	startupErr error // why the last startup attempt failed
+	checks     []func() HealthCheck
+}
+
+// NewHealthHandler creates a health handler for a service that is starting
+func NewHealthHandler() *HealthHandler {
+	return &HealthHandler{}
+}
+
+// SetChecks marks startup as finished and sets the dependency checks /readyz runs
+func (h *HealthHandler) SetChecks(checks ...func() HealthCheck) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = true
+	h.checks = checks
+}
+
+// SetStartupError records why loading the service's data failed, reported
+// by /readyz until startup succeeds
+func (h *HealthHandler) SetStartupError(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.startupErr = err
+}
+
+// HandleLiveness handles GET /healthz. It only shows the process is serving
+func (h *HealthHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, map[string]string{"status": CheckOK})
+}
+
+// HandleReadiness handles GET /readyz. The service is not ready (503) while
+// starting or when any check fails, and degraded (200) when a check is degraded
+func (h *HealthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	started, startupErr, checks := h.started, h.startupErr, h.checks
+	h.mu.RUnlock()
+
+	results := []HealthCheck{}
+	if !started {
+		startup := HealthCheck{Name: "startup", Status: CheckFailing, Message: "loading inventory and rules"}
+		if startupErr != nil {
+			startup.Message = "loading failed, retrying: " + startupErr.Error()
+		}
+		results = append(results, startup)
+	}
+	for _, check := range checks {
+		results = append(results, check())
+	}
+
+	status, code := ReadinessReady, http.StatusOK
+	for _, result := range results {
+		switch result.Status {
+		case CheckFailing:
+			status, code = ReadinessNotReady, http.StatusServiceUnavailable
+		case CheckDegraded:
+			if status == ReadinessReady {
+				status = ReadinessDegraded
+			}
+		}
+	}
+
+	writeHealth(w, code, Readiness{Status: status, Checks: results})
+}
+
+// writeHealth writes a probe response as JSON
+func writeHealth(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(body)
+	if err != nil {
+		slog.Error("encoding response", "error", err)
+	}
+}
+
+// StartupGate answers 503 Service Unavailable until Open hands it the
+// handler to serve, so the server can listen while data is still loading
+type StartupGate struct {
+	handler atomic.Pointer[http.Handler]
+}
+
+// Open starts passing requests to handler
+func (g *StartupGate) Open(handler http.Handler) {
+	g.handler.Store(&handler)
+}
+
+// ServeHTTP serves the request once the gate is open
+func (g *StartupGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler := g.handler.Load()
+	if handler == nil {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Service is starting, try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	(*handler).ServeHTTP(w, r)
+}
+
+// InventoryStats is a snapshot of an inventory source's activity
+type InventoryStats struct {
+	Lookups    Histogram         // latency in seconds of lookups that reached the source
+	Errors     map[string]uint64 // failed lookups by kind: unavailable, timeout or other
+	Items      int               // rows loaded; -1 if the source does not hold them in memory
+	LastReload time.Time         // last successful load or reload; zero if none
+}
+
+// inventorySizer is implemented by adapters that hold their rows in memory
+type inventorySizer interface {
+	Size() int
+}
+
+// inventoryDater is implemented by adapters that know when the data they
+// hold in memory was produced
+type inventoryDater interface {
+	AsOf() time.Time
+}
+
+// dataAsOf returns when the adapter's loaded data was produced, or the zero
+// time if it does not know
+func dataAsOf(adapter InventoryAdapter) time.Time {
+	if dater, ok := adapter.(inventoryDater); ok {
+		return dater.AsOf()
+	}
+	return time.Time{}
+}
+
+// MonitoredInventoryAdapter records how an inventory source is doing so
+// readiness and metrics can report it: whether it has loaded, whether
+// lookups are failing, how long they take and how old the data it serves is
+type MonitoredInventoryAdapter struct {
+	name   string
+	inner  InventoryAdapter
+	maxAge time.Duration // data older than this is stale; 0 disables the check
+
+	mu         sync.Mutex
+	loaded     bool
+	loadErr    error // last LoadInventory error, nil once a load succeeds
+	lastReload time.Time
+	failures   int // consecutive lookups the source failed to answer
+	lastErr    error
+	lastAsOf   time.Time // data timestamp of the last load, reload or answered lookup
+	lookups    *Histogram
+	errors     map[string]uint64
+}
+
+// NewMonitoredInventoryAdapter wraps an adapter so its health can be checked
+func NewMonitoredInventoryAdapter(name string, inner InventoryAdapter, maxAge time.Duration) *MonitoredInventoryAdapter {
+	return &MonitoredInventoryAdapter{
+		name:    name,
+		inner:   inner,
+		maxAge:  maxAge,
+		lookups: NewHistogram(defaultLatencyBuckets),
+		errors:  map[string]uint64{},
+	}
+}
+
+// LoadInventory loads the wrapped adapter and records the outcome
+func (m *MonitoredInventoryAdapter) LoadInventory() error {
+	err := m.inner.LoadInventory()
+	asOf := dataAsOf(m.inner)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadErr = err
+	if err == nil {
+		m.loaded = true
+		m.lastReload = now()
+		m.recordAsOf(asOf)
+	}
+	return err
+}
+
+// RecordReload records the outcome of a reload done outside LoadInventory,
+// such as the directory adapter's watcher. changed lists what was reloaded
+func (m *MonitoredInventoryAdapter) RecordReload(changed []string, err error) {
+	asOf := dataAsOf(m.inner)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.loadErr = err
+	if err == nil && len(changed) > 0 {
+		m.lastReload = now()
+		m.recordAsOf(asOf)
+	}
+}
+
+// recordAsOf sets the age of the data served, if known. The caller holds m.mu
+func (m *MonitoredInventoryAdapter) recordAsOf(asOf time.Time) {
+	if !asOf.IsZero() {
+		m.lastAsOf = asOf
+	}
+}
+
+// GetStockLevel retrieves the stock level and records the outcome
+func (m *MonitoredInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	info, err := m.GetStockInfo(ctx, productID, warehouse)
+	return info.StockLevel, err
+}
+
+// GetStockInfo retrieves the stock level and its source and records the outcome.
+// Authoritative answers such as "not found" count as the source working;
+// lookups the caller cancelled or ran out of time for do not count at all
+func (m *MonitoredInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	start := time.Now()
+	info, err := lookupStock(ctx, m.inner, productID, warehouse)
+	elapsed := time.Since(start)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups.Observe(elapsed.Seconds())
+	switch {
+	case err == nil:
+		m.failures = 0
+		m.recordAsOf(info.AsOf)
+	case isAuthoritative(err):
+		m.failures = 0
+	case ctx.Err() != nil:
+		// The caller went away or hit its own deadline; that says nothing
+		// about the source
+	default:
+		m.failures++
+		m.lastErr = err
+		m.errors[errorKind(err)]++
+	}
+	return info, err
+}
+
+// errorKind classifies a failed lookup for metrics
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrSourceUnavailable):
+		return "unavailable"
+	default:
+		return "other"
+	}
+}
+
+// Stats returns a snapshot of the source's activity
+func (m *MonitoredInventoryAdapter) Stats() InventoryStats {
+	m.mu.Lock()
+	stats := InventoryStats{
+		Lookups:    m.lookups.Snapshot(),
+		Errors:     make(map[string]uint64, len(m.errors)),
+		Items:      -1,
+		LastReload: m.lastReload,
+	}
+	for kind, count := range m.errors {
+		stats.Errors[kind] = count
+	}
+	m.mu.Unlock()
+
+	if sizer, ok := m.inner.(inventorySizer); ok {
+		stats.Items = sizer.Size()
+	}
+	return stats
+}
+
+// Check reports the source as failing until it has loaded, and as degraded
+// while lookups fail, after a failed reload or when its data is stale
+func (m *MonitoredInventoryAdapter) Check() HealthCheck {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	check := HealthCheck{Name: m.name, Status: CheckOK}
+	if !m.lastAsOf.IsZero() {
+		asOf := m.lastAsOf
+		check.AsOf = &asOf
+	}
+
+	switch {
+	case !m.loaded:
+		check.Status = CheckFailing
+		check.Message = "inventory not loaded"
+		if m.loadErr != nil {
+			check.Message += ": " + m.loadErr.Error()
+		}
+	case m.failures > 0:
+		check.Status = CheckDegraded
+		check.Message = fmt.Sprintf("last %d lookups failed: %v", m.failures, m.lastErr)
+	case m.loadErr != nil:
+		check.Status = CheckDegraded
+		check.Message = "last reload failed: " + m.loadErr.Error()
+	case m.maxAge > 0 && !m.lastAsOf.IsZero() && now().Sub(m.lastAsOf) > m.maxAge:
+		check.Status = CheckDegraded
+		check.Message = fmt.Sprintf("inventory data is %s old (max %s)", now().Sub(m.lastAsOf).Round(time.Second), m.maxAge)
+	}
+	return check
+}
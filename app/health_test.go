@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ToggleInventoryAdapter is a test double for a source that can be taken down
+type ToggleInventoryAdapter struct {
+	SnapshotInventoryAdapter
+	down bool
+}
+
+func (a *ToggleInventoryAdapter) LoadInventory() error {
+	if a.down {
+		return fmt.Errorf("%w: connection refused", ErrSourceUnavailable)
+	}
+	return nil
+}
+
+func (a *ToggleInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	if a.down {
+		return StockInfo{}, fmt.Errorf("%w: connection refused", ErrSourceUnavailable)
+	}
+	return a.SnapshotInventoryAdapter.GetStockInfo(ctx, productID, warehouse)
+}
+
+func newToggleAdapter(asOf time.Time) *ToggleInventoryAdapter {
+	return &ToggleInventoryAdapter{SnapshotInventoryAdapter: SnapshotInventoryAdapter{NewMockInventoryAdapter(), asOf}}
+}
+
+// getReadiness calls /readyz and decodes the response
+func getReadiness(t *testing.T, health *HealthHandler) (int, Readiness) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	health.HandleReadiness(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var readiness Readiness
+	err := json.Unmarshal(rr.Body.Bytes(), &readiness)
+	if err != nil {
+		t.Fatalf("Failed to decode readiness: %v", err)
+	}
+	return rr.Code, readiness
+}
+
+func TestHealthHandler_Liveness(t *testing.T) {
+	rr := httptest.NewRecorder()
+	NewHealthHandler().HandleLiveness(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Body.String() != "{\n  \"status\": \"ok\"\n}\n" {
+		t.Errorf("Unexpected liveness body %q", rr.Body.String())
+	}
+}
+
+func TestHealthHandler_NotReadyWhileStarting(t *testing.T) {
+	health := NewHealthHandler()
+
+	code, readiness := getReadiness(t, health)
+	if code != http.StatusServiceUnavailable || readiness.Status != ReadinessNotReady {
+		t.Fatalf("Expected 503 not_ready, got %d %s", code, readiness.Status)
+	}
+	if len(readiness.Checks) != 1 || readiness.Checks[0].Name != "startup" || readiness.Checks[0].Status != CheckFailing {
+		t.Errorf("Expected a failing startup check, got %+v", readiness.Checks)
+	}
+
+	health.SetStartupError(errors.New("failed to load inventory"))
+	_, readiness = getReadiness(t, health)
+	if readiness.Checks[0].Message != "loading failed, retrying: failed to load inventory" {
+		t.Errorf("Expected the startup error in the check, got %q", readiness.Checks[0].Message)
+	}
+}
+
+func TestHealthHandler_ReadinessFromChecks(t *testing.T) {
+	check := func(status string) func() HealthCheck {
+		return func() HealthCheck { return HealthCheck{Name: status, Status: status} }
+	}
+	tests := []struct {
+		name       string
+		checks     []func() HealthCheck
+		wantCode   int
+		wantStatus string
+	}{
+		{"no checks", nil, http.StatusOK, ReadinessReady},
+		{"all ok", []func() HealthCheck{check(CheckOK), check(CheckOK)}, http.StatusOK, ReadinessReady},
+		{"one degraded", []func() HealthCheck{check(CheckOK), check(CheckDegraded)}, http.StatusOK, ReadinessDegraded},
+		{"one failing", []func() HealthCheck{check(CheckFailing), check(CheckDegraded)}, http.StatusServiceUnavailable, ReadinessNotReady},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := NewHealthHandler()
+			health.SetChecks(tt.checks...)
+
+			code, readiness := getReadiness(t, health)
+			if code != tt.wantCode || readiness.Status != tt.wantStatus {
+				t.Errorf("Expected %d %s, got %d %s", tt.wantCode, tt.wantStatus, code, readiness.Status)
+			}
+			if len(readiness.Checks) != len(tt.checks) {
+				t.Errorf("Expected %d checks in the body, got %d", len(tt.checks), len(readiness.Checks))
+			}
+		})
+	}
+}
+
+func TestStartupGate_UnavailableUntilOpen(t *testing.T) {
+	gate := &StartupGate{}
+
+	rr := httptest.NewRecorder()
+	gate.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/warehouses", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 before open, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header before open")
+	}
+
+	gate.Open(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	rr = httptest.NewRecorder()
+	gate.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/warehouses", nil))
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected the opened handler to serve, got %d", rr.Code)
+	}
+}
+
+func TestMonitoredAdapter_FailingUntilLoaded(t *testing.T) {
+	source := newToggleAdapter(now())
+	source.down = true
+	monitor := NewMonitoredInventoryAdapter("inventory", source, 0)
+
+	if err := monitor.LoadInventory(); err == nil {
+		t.Fatal("Expected the load to fail")
+	}
+	check := monitor.Check()
+	if check.Status != CheckFailing {
+		t.Errorf("Expected failing before a successful load, got %s", check.Status)
+	}
+
+	source.down = false
+	if err := monitor.LoadInventory(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if check := monitor.Check(); check.Status != CheckOK {
+		t.Errorf("Expected ok after loading, got %s (%s)", check.Status, check.Message)
+	}
+}
+
+func TestMonitoredAdapter_DegradedWhileLookupsFail(t *testing.T) {
+	source := newToggleAdapter(now())
+	monitor := NewMonitoredInventoryAdapter("inventory", source, 0)
+	monitor.LoadInventory()
+
+	source.down = true
+	monitor.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+	monitor.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+	check := monitor.Check()
+	if check.Status != CheckDegraded {
+		t.Fatalf("Expected degraded while the source fails, got %s", check.Status)
+	}
+	if check.Message != "last 2 lookups failed: inventory source unavailable: connection refused" {
+		t.Errorf("Unexpected message %q", check.Message)
+	}
+
+	source.down = false
+	monitor.GetStockInfo(t.Context(), "PROD-999", "DE-Berlin") // not found is an answer
+	if check := monitor.Check(); check.Status != CheckOK {
+		t.Errorf("Expected ok once the source answers again, got %s", check.Status)
+	}
+}
+
+func TestMonitoredAdapter_DegradedAfterFailedReload(t *testing.T) {
+	monitor := NewMonitoredInventoryAdapter("inventory", newToggleAdapter(now()), 0)
+	monitor.LoadInventory()
+
+	monitor.RecordReload(nil, errors.New("inventory.d/eu.json: invalid JSON"))
+	if check := monitor.Check(); check.Status != CheckDegraded {
+		t.Errorf("Expected degraded after a failed reload, got %s", check.Status)
+	}
+	monitor.RecordReload([]string{"eu.json"}, nil)
+	if check := monitor.Check(); check.Status != CheckOK {
+		t.Errorf("Expected ok after a good reload, got %s", check.Status)
+	}
+}
+
+func TestMonitoredAdapter_DegradedWhenStale(t *testing.T) {
+	asOf := now().Add(-3 * time.Hour)
+	tests := []struct {
+		name   string
+		maxAge time.Duration
+		want   string
+	}{
+		{"within max age", 4 * time.Hour, CheckOK},
+		{"older than max age", time.Hour, CheckDegraded},
+		{"check disabled", 0, CheckOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := NewMonitoredInventoryAdapter("inventory", newToggleAdapter(asOf), tt.maxAge)
+			monitor.LoadInventory()
+			monitor.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+
+			check := monitor.Check()
+			if check.Status != tt.want {
+				t.Errorf("Expected %s, got %s (%s)", tt.want, check.Status, check.Message)
+			}
+			if check.AsOf == nil || !check.AsOf.Equal(asOf) {
+				t.Errorf("Expected as_of %v, got %v", asOf, check.AsOf)
+			}
+		})
+	}
+}
+
+func TestMonitoredAdapter_StaleWithoutLookups(t *testing.T) {
+	dir := t.TempDir()
+	stale := now().Add(-3 * time.Hour)
+	writeInventoryFile(t, dir, "inventory.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 10}]`, stale)
+	monitor := NewMonitoredInventoryAdapter("inventory", NewFileInventoryAdapter(filepath.Join(dir, "inventory.json")), time.Hour)
+
+	// An idle instance serves the loaded file, so its age counts
+	if err := monitor.LoadInventory(); err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	check := monitor.Check()
+	if check.Status != CheckDegraded || check.AsOf == nil || !check.AsOf.Equal(stale) {
+		t.Errorf("Expected degraded with as_of %v before any lookup, got %s (%v)", stale, check.Status, check.AsOf)
+	}
+}
+
+func TestMonitoredAdapter_ReloadRefreshesDataAge(t *testing.T) {
+	dir := t.TempDir()
+	writeInventoryFile(t, dir, "berlin.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 10}]`, now().Add(-3*time.Hour))
+	source := NewDirectoryInventoryAdapter(dir)
+	monitor := NewMonitoredInventoryAdapter("inventory", source, time.Hour)
+	monitor.LoadInventory()
+	if check := monitor.Check(); check.Status != CheckDegraded {
+		t.Fatalf("Expected degraded with a stale export, got %s", check.Status)
+	}
+
+	writeInventoryFile(t, dir, "berlin.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 12}]`, now())
+	changed, err := source.ReloadChanged()
+	monitor.RecordReload(changed, err)
+	if check := monitor.Check(); check.Status != CheckOK {
+		t.Errorf("Expected ok once a fresh export is reloaded, got %s (%s)", check.Status, check.Message)
+	}
+}
+
+func TestMonitoredAdapter_CallerDeadlineIsNotAFailure(t *testing.T) {
+	monitor := NewMonitoredInventoryAdapter("inventory", newToggleAdapter(now()), 0)
+	monitor.LoadInventory()
+
+	ctx, cancel := context.WithDeadline(t.Context(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := monitor.GetStockInfo(ctx, "PROD-123", "DE-Berlin"); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected a timeout, got %v", err)
+	}
+	if check := monitor.Check(); check.Status != CheckOK {
+		t.Errorf("Expected the caller's deadline not to degrade readiness, got %s (%s)", check.Status, check.Message)
+	}
+}
+
+func TestTenantRegistry_ChecksNamedPerTenant(t *testing.T) {
+	tenants := newTestTenants(t)
+
+	checks := tenants.Checks()
+	if len(checks) != len(tenants.List()) {
+		t.Fatalf("Expected one check per tenant, got %d", len(checks))
+	}
+	for i, tenant := range tenants.List() {
+		check := checks[i]()
+		if check.Name != "inventory:"+tenant.ID || check.Status != CheckOK {
+			t.Errorf("Unexpected check for tenant %s: %+v", tenant.ID, check)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// InboundShipment is stock on a purchase order that is expected to arrive at a warehouse
+type InboundShipment struct {
+	PurchaseOrder string `json:"purchase_order"`
+	ProductID     string `json:"product_id"`
+	Warehouse     string `json:"warehouse"`
+	Quantity      int    `json:"quantity"`
+	ExpectedDate  string `json:"expected_date"` // "2006-01-02"
+
+	expected time.Time // parsed ExpectedDate
+}
+
+// InboundSchedule holds open purchase orders, loaded from a JSON file
+type InboundSchedule struct {
+	filePath  string
+	shipments map[stockKey][]InboundShipment // ordered by expected date
+}
+
+// NewInboundSchedule creates a schedule backed by the given JSON file
+func NewInboundSchedule(filePath string) *InboundSchedule {
+	return &InboundSchedule{
+		filePath:  filePath,
+		shipments: map[stockKey][]InboundShipment{},
+	}
+}
+
+// Load reads and validates the inbound shipments
+func (s *InboundSchedule) Load() error {
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read inbound schedule: %w", err)
+	}
+
+	var shipments []InboundShipment
+	err = json.Unmarshal(data, &shipments)
+	if err != nil {
+		return fmt.Errorf("failed to parse inbound schedule JSON: %w", err)
+	}
+
+	return s.setShipments(shipments)
+}
+
+// setShipments validates shipments and replaces the schedule with them
+func (s *InboundSchedule) setShipments(shipments []InboundShipment) error {
+	byKey := map[stockKey][]InboundShipment{}
+	for _, shipment := range shipments {
+		if shipment.ProductID == "" || shipment.Warehouse == "" {
+			return fmt.Errorf("inbound shipment %s: product_id and warehouse are required", shipment.PurchaseOrder)
+		}
+		if shipment.Quantity <= 0 {
+			return fmt.Errorf("inbound shipment %s: quantity must be greater than 0", shipment.PurchaseOrder)
+		}
+		expected, err := time.Parse(time.DateOnly, shipment.ExpectedDate)
+		if err != nil {
+			return fmt.Errorf("inbound shipment %s: invalid expected_date %q (want YYYY-MM-DD)", shipment.PurchaseOrder, shipment.ExpectedDate)
+		}
+		shipment.expected = expected
+
+		key := stockKey{shipment.ProductID, shipment.Warehouse}
+		byKey[key] = append(byKey[key], shipment)
+	}
+
+	for _, list := range byKey {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].expected.Before(list[j].expected) })
+	}
+	s.shipments = byKey
+	return nil
+}
+
+// Expected returns the shipments of a product to a warehouse, earliest first
+func (s *InboundSchedule) Expected(productID, warehouse string) []InboundShipment {
+	return s.shipments[stockKey{productID, warehouse}]
+}
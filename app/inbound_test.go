@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newATPService returns a service over the mock adapter with inbound shipments
+// for PROD-789 at DE-Berlin, which has no stock on hand
+func newATPService(t *testing.T) *AvailabilityService {
+	t.Helper()
+	inbound := NewInboundSchedule("")
+	err := inbound.setShipments([]InboundShipment{
+		{PurchaseOrder: "PO-2", ProductID: "PROD-789", Warehouse: "DE-Berlin", Quantity: 20, ExpectedDate: "2024-01-22"},
+		{PurchaseOrder: "PO-1", ProductID: "PROD-789", Warehouse: "DE-Berlin", Quantity: 30, ExpectedDate: "2024-01-15"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set shipments: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithInboundSchedule(inbound))
+}
+
+func TestCheckAvailability_ATPCountsInboundByShipDate(t *testing.T) {
+	service := newATPService(t)
+
+	// 30 units arrive on 2024-01-15: 30 - 10% = 27 available
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 5, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-15"})
+
+	if !resp.Available {
+		t.Errorf("Expected available=true with inbound stock, got false. Reason: %s", resp.Reason)
+	}
+	if resp.InboundQuantity != 30 || resp.AvailableQuantity != 27 {
+		t.Errorf("Expected inbound=30 and available_quantity=27, got %d and %d", resp.InboundQuantity, resp.AvailableQuantity)
+	}
+	if resp.EarliestPromiseDate != "2024-01-15" {
+		t.Errorf("Expected earliest_promise_date=2024-01-15, got %q", resp.EarliestPromiseDate)
+	}
+}
+
+func TestCheckAvailability_ATPShipDateBeforeInbound(t *testing.T) {
+	service := newATPService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 5, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-12"})
+
+	if resp.Available {
+		t.Error("Expected available=false before any inbound stock arrives")
+	}
+	if resp.ReasonCode != ReasonOutOfStock {
+		t.Errorf("Expected reason_code=%s, got %s", ReasonOutOfStock, resp.ReasonCode)
+	}
+	if resp.EarliestPromiseDate != "2024-01-15" {
+		t.Errorf("Expected earliest_promise_date=2024-01-15, got %q", resp.EarliestPromiseDate)
+	}
+}
+
+func TestCheckAvailability_ATPEarliestPromiseNeedsSeveralShipments(t *testing.T) {
+	service := newATPService(t)
+
+	// 27 available after the first shipment, 45 after the second
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 40, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-15"})
+
+	if resp.Available || resp.ReasonCode != ReasonInsufficientStock {
+		t.Errorf("Expected insufficient stock by ship date, got %+v", resp)
+	}
+	if resp.EarliestPromiseDate != "2024-01-22" {
+		t.Errorf("Expected earliest_promise_date=2024-01-22, got %q", resp.EarliestPromiseDate)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 100, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-30"})
+	if resp.EarliestPromiseDate != "" {
+		t.Errorf("Expected no promise date when inbound never covers the quantity, got %q", resp.EarliestPromiseDate)
+	}
+}
+
+func TestCheckAvailability_ATPOnHandPromisesToday(t *testing.T) {
+	service := newATPService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-20"})
+
+	if !resp.Available {
+		t.Errorf("Expected available=true from stock on hand, got false. Reason: %s", resp.Reason)
+	}
+	if resp.EarliestPromiseDate != "2024-01-10" {
+		t.Errorf("Expected earliest_promise_date=2024-01-10 (today), got %q", resp.EarliestPromiseDate)
+	}
+}
+
+func TestCheckAvailability_ATPSkipsPastDueShipments(t *testing.T) {
+	inbound := NewInboundSchedule("")
+	err := inbound.setShipments([]InboundShipment{
+		{PurchaseOrder: "PO-0", ProductID: "PROD-789", Warehouse: "DE-Berlin", Quantity: 30, ExpectedDate: "2024-01-08"},
+		{PurchaseOrder: "PO-1", ProductID: "PROD-789", Warehouse: "DE-Berlin", Quantity: 10, ExpectedDate: "2024-01-10"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set shipments: %v", err)
+	}
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithInboundSchedule(inbound))
+
+	// PO-0 was due two days ago: received or late, it cannot count again
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 5, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-15"})
+	if resp.InboundQuantity != 10 || resp.AvailableQuantity != 9 {
+		t.Errorf("Expected only today's 10 units inbound, got inbound=%d and available_quantity=%d", resp.InboundQuantity, resp.AvailableQuantity)
+	}
+}
+
+func TestCheckAvailability_ATPConvertsInboundStockUnits(t *testing.T) {
+	catalog := NewProductCatalog("products.json")
+	if err := catalog.Load(); err != nil {
+		t.Fatalf("Failed to load catalog: %v", err)
+	}
+	inbound := NewInboundSchedule("")
+	err := inbound.setShipments([]InboundShipment{
+		{PurchaseOrder: "PO-3", ProductID: "PROD-909", Warehouse: "DE-Berlin", Quantity: 10, ExpectedDate: "2024-01-15"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set shipments: %v", err)
+	}
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithProductCatalog(catalog), WithInboundSchedule(inbound))
+
+	// PROD-909 is stocked in cases of 24: 5 cases on hand and 10 inbound are
+	// 360 each, 324 after reserve
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-909", Quantity: 300, WarehouseLocation: "DE-Berlin", ShipDate: "2024-01-15"})
+	if !resp.Available || resp.InboundQuantity != 240 || resp.AvailableQuantity != 324 {
+		t.Errorf("Expected 240 each inbound and 324 available, got %+v", resp)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-909", Quantity: 300, WarehouseLocation: "DE-Berlin"})
+	if resp.EarliestAvailableDate == nil || *resp.EarliestAvailableDate != "2024-01-15" {
+		t.Errorf("Expected the inbound cases to cover 300 each on 2024-01-15, got %v", resp.EarliestAvailableDate)
+	}
+}
+
+func TestHandleCheckAvailability_ValidatesShipDate(t *testing.T) {
+	tests := []struct {
+		shipDate string
+		status   int
+	}{
+		{"2024-01-20", http.StatusOK},
+		{"20-01-2024", http.StatusBadRequest},
+		{"2024-01-09", http.StatusBadRequest}, // yesterday
+	}
+
+	for _, tt := range tests {
+		body := `{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "DE-Berlin", "ship_date": "` + tt.shipDate + `"}`
+		rec, _ := postAvailability(t, NewMockInventoryAdapter(), body)
+		if rec.Code != tt.status {
+			t.Errorf("ship_date %s: expected status %d, got %d", tt.shipDate, tt.status, rec.Code)
+		}
+	}
+}
+
+func TestHandleCheckAvailability_ShipDateUsesWarehouseDay(t *testing.T) {
+	registry := NewWarehouseRegistry("")
+	err := registry.setWarehouses([]Warehouse{
+		{Code: "JP-Tokyo", TimeZone: "Asia/Tokyo", Active: true},
+		{Code: "US-NewYork", TimeZone: "America/New_York", Active: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set warehouses: %v", err)
+	}
+	handler := NewAvailabilityHandler(NewAvailabilityService(NewMockInventoryAdapter(), WithWarehouseRegistry(registry)), registry)
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		warehouse string
+		status    int
+	}{
+		// 20:00 UTC on the 10th is already the 11th in Tokyo
+		{"past in Tokyo", time.Date(2024, time.January, 10, 20, 0, 0, 0, time.UTC), "JP-Tokyo", http.StatusBadRequest},
+		// 02:00 UTC on the 11th is still the 10th in New York
+		{"today in New York", time.Date(2024, time.January, 11, 2, 0, 0, 0, time.UTC), "US-NewYork", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := now
+			now = func() time.Time { return tt.now }
+			defer func() { now = restore }()
+
+			body := `{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "` + tt.warehouse + `", "ship_date": "2024-01-10"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			handler.HandleCheckAvailability(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
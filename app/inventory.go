@@ -1,22 +1,103 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"time"
 )
 
+// Errors returned by inventory adapters. Callers should match them with errors.Is
+var (
+	// ErrProductNotFound means the source answered and has no such product at the warehouse
+	ErrProductNotFound = errors.New("product not found")
+	// ErrUnknownWarehouse means the source answered and does not know the warehouse at all
+	ErrUnknownWarehouse = errors.New("unknown warehouse")
+	// ErrSourceUnavailable means the source could not answer at all
+	ErrSourceUnavailable = errors.New("inventory source unavailable")
+	// ErrTimeout means the source did not answer in time
+	ErrTimeout = errors.New("inventory source timed out")
+)
+
+// contextError reports a lookup abandoned because its context is done, by
+// deadline or because the caller went away, as a timeout. The context error
+// stays in the chain for errors.Is
+func contextError(err error) error {
+	return fmt.Errorf("%w: %w", ErrTimeout, err)
+}
+
+// isAuthoritative reports whether err is an answer from the source rather
+// than a failure to answer
+func isAuthoritative(err error) bool {
+	return errors.Is(err, ErrProductNotFound) || errors.Is(err, ErrUnknownWarehouse)
+}
+
 // InventoryAdapter defines the interface for fetching inventory data
 // This allows easy switching between different data sources (file, API, database, etc.)
+// Lookups take the request's context; adapters that block must give up when
+// it is done, reporting ErrTimeout
 type InventoryAdapter interface {
-	GetStockLevel(productID, warehouse string) (int, error)
+	GetStockLevel(ctx context.Context, productID, warehouse string) (int, error)
 	LoadInventory() error
 }
 
+// StockInfo is a stock level together with the source that reported it
+type StockInfo struct {
+	StockLevel int
+	Source     string    // name of the source that answered
+	AsOf       time.Time // when the source data was produced
+	Lots       []Lot     // lots making up the stock, if it is tracked by lot
+}
+
+// StockInfoProvider is implemented by adapters that can report where a stock
+// level came from and how fresh it is
+type StockInfoProvider interface {
+	GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error)
+}
+
+// lookupStock fetches stock from any adapter, using GetStockInfo when the
+// adapter supports it. Nothing is looked up once ctx is done
+func lookupStock(ctx context.Context, adapter InventoryAdapter, productID, warehouse string) (StockInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return StockInfo{}, contextError(err)
+	}
+	if provider, ok := adapter.(StockInfoProvider); ok {
+		return provider.GetStockInfo(ctx, productID, warehouse)
+	}
+	stock, err := adapter.GetStockLevel(ctx, productID, warehouse)
+	if err != nil {
+		return StockInfo{}, err
+	}
+	return StockInfo{StockLevel: stock}, nil
+}
+
+// recordLookup records a lookup's answer on its span. Only failures to
+// answer mark the span as failed; not found is an answer
+func recordLookup(span *Span, info StockInfo, err error) {
+	switch {
+	case err == nil:
+		span.SetAttributes(slog.Int("stock_level", info.StockLevel))
+		if info.Source != "" {
+			span.SetAttributes(slog.String("source", info.Source))
+		}
+	case isAuthoritative(err):
+		span.SetAttributes(slog.String("error", err.Error()))
+	default:
+		span.RecordError(err)
+	}
+}
+
 // FileInventoryAdapter implements InventoryAdapter using a JSON file as data source
 type FileInventoryAdapter struct {
 	filePath  string
 	inventory []InventoryItem
+	modTime   time.Time
 }
 
 // NewFileInventoryAdapter creates a new file-based inventory adapter
@@ -34,49 +115,180 @@ func (f *FileInventoryAdapter) LoadInventory() error {
 	if err != nil {
 		return fmt.Errorf("failed to read inventory file: %w", err)
 	}
+	info, err := os.Stat(f.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat inventory file: %w", err)
+	}
 
 	// Parse JSON into inventory items
-	err = json.Unmarshal(data, &f.inventory)
+	var inventory []InventoryItem
+	err = json.Unmarshal(data, &inventory)
 	if err != nil {
 		return fmt.Errorf("failed to parse inventory JSON: %w", err)
 	}
+	for _, item := range inventory {
+		if err := validateLots(item); err != nil {
+			return err
+		}
+	}
+	f.inventory = inventory
+	f.modTime = info.ModTime()
 
 	return nil
 }
 
+// Size returns the number of inventory rows loaded
+func (f *FileInventoryAdapter) Size() int {
+	return len(f.inventory)
+}
+
+// AsOf returns the modification time of the loaded file
+func (f *FileInventoryAdapter) AsOf() time.Time {
+	return f.modTime
+}
+
 // GetStockLevel retrieves the stock level for a product at a specific warehouse
-func (f *FileInventoryAdapter) GetStockLevel(productID, warehouse string) (int, error) {
+func (f *FileInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	item, err := f.findItem(productID, warehouse)
+	return item.StockLevel, err
+}
+
+// GetStockInfo retrieves the stock level and lots along with the file they
+// were read from. The file's modification time is reported as the data's age
+func (f *FileInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	item, err := f.findItem(productID, warehouse)
+	if err != nil {
+		return StockInfo{}, err
+	}
+	return StockInfo{StockLevel: item.StockLevel, Source: f.filePath, AsOf: f.modTime, Lots: item.Lots}, nil
+}
+
+// findItem returns the inventory row for a product at a warehouse
+func (f *FileInventoryAdapter) findItem(productID, warehouse string) (InventoryItem, error) {
+	knownWarehouse := false
 	for _, item := range f.inventory {
-		if item.ProductID == productID && item.Warehouse == warehouse {
-			return item.StockLevel, nil
+		if item.Warehouse != warehouse {
+			continue
 		}
+		knownWarehouse = true
+		if item.ProductID == productID {
+			return item, nil
+		}
+	}
+	if !knownWarehouse {
+		return InventoryItem{}, fmt.Errorf("%w: %s", ErrUnknownWarehouse, warehouse)
 	}
-	return 0, fmt.Errorf("product %s not found in warehouse %s", productID, warehouse)
+	return InventoryItem{}, fmt.Errorf("%w: %s in warehouse %s", ErrProductNotFound, productID, warehouse)
 }
 
-// APIInventoryAdapter is a placeholder for future API-based inventory
-// This demonstrates how easy it is to swap implementations with the adapter pattern
+// APIInventoryAdapter implements InventoryAdapter by querying a remote inventory API
+//
+//	GET {apiURL}/inventory?product={productID}&warehouse={warehouse}
+//
+// A 200 response carries an InventoryItem. A 404 response may carry
+// {"code": "unknown_warehouse"} to distinguish an unknown warehouse from a
+// missing product.
 type APIInventoryAdapter struct {
 	apiURL string
-	// Add fields for API client, authentication, etc.
+	client *http.Client
 }
 
+// defaultAPITimeout bounds each call to the inventory API
+const defaultAPITimeout = 2 * time.Second
+
 // NewAPIInventoryAdapter creates a new API-based inventory adapter
 func NewAPIInventoryAdapter(apiURL string) *APIInventoryAdapter {
 	return &APIInventoryAdapter{
 		apiURL: apiURL,
+		client: &http.Client{Timeout: defaultAPITimeout},
 	}
 }
 
-// LoadInventory would initialize the API connection
+// LoadInventory validates the API URL. Stock is fetched per request, so there
+// is nothing to preload
 func (a *APIInventoryAdapter) LoadInventory() error {
-	// TODO: Initialize API client, authenticate, etc.
-	return fmt.Errorf("API adapter not yet implemented")
+	u, err := url.Parse(a.apiURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("invalid inventory API URL %q", a.apiURL)
+	}
+	return nil
+}
+
+// GetStockLevel fetches the stock level from the inventory API
+func (a *APIInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	info, err := a.GetStockInfo(ctx, productID, warehouse)
+	return info.StockLevel, err
 }
 
-// GetStockLevel would fetch stock from an external API
-func (a *APIInventoryAdapter) GetStockLevel(productID, warehouse string) (int, error) {
-	// TODO: Make API call to fetch stock level
-	// Example: GET /api/inventory?product={productID}&warehouse={warehouse}
-	return 0, fmt.Errorf("API adapter not yet implemented")
+// GetStockInfo fetches the stock level and any lots from the inventory API.
+// The call is bounded by both ctx and the client timeout, is traced as a
+// client span, and carries the request ID and trace context so the
+// inventory service can correlate its logs and traces
+func (a *APIInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (info StockInfo, err error) {
+	ctx, span := startSpan(ctx, "GET /inventory", SpanKindClient,
+		slog.String("http.request.method", http.MethodGet),
+		slog.String("product_id", productID),
+		slog.String("warehouse", warehouse))
+	defer func() {
+		recordLookup(span, info, err)
+		span.End()
+	}()
+
+	query := url.Values{}
+	query.Set("product", productID)
+	query.Set("warehouse", warehouse)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.apiURL+"/inventory?"+query.Encode(), nil)
+	if err != nil {
+		return StockInfo{}, fmt.Errorf("%w: %v", ErrSourceUnavailable, err)
+	}
+	if id := requestIDFromContext(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+	injectTraceContext(ctx, req.Header)
+	span.SetAttributes(slog.String("url.full", req.URL.Redacted()))
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return StockInfo{}, contextError(ctxErr)
+		}
+		if isTimeout(err) {
+			return StockInfo{}, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return StockInfo{}, fmt.Errorf("%w: %v", ErrSourceUnavailable, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(slog.Int("http.response.status_code", resp.StatusCode))
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var item InventoryItem
+		if err := json.NewDecoder(resp.Body).Decode(&item); err != nil {
+			return StockInfo{}, fmt.Errorf("%w: invalid API response: %v", ErrSourceUnavailable, err)
+		}
+		return StockInfo{StockLevel: item.StockLevel, Lots: item.Lots}, nil
+	case resp.StatusCode == http.StatusNotFound:
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Code == "unknown_warehouse" {
+			return StockInfo{}, fmt.Errorf("%w: %s", ErrUnknownWarehouse, warehouse)
+		}
+		return StockInfo{}, fmt.Errorf("%w: %s in warehouse %s", ErrProductNotFound, productID, warehouse)
+	case resp.StatusCode == http.StatusGatewayTimeout:
+		return StockInfo{}, fmt.Errorf("%w: API returned %s", ErrTimeout, resp.Status)
+	default:
+		return StockInfo{}, fmt.Errorf("%w: API returned %s", ErrSourceUnavailable, resp.Status)
+	}
+}
+
+// isTimeout reports whether err is a client-side or network timeout
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CacheOptions configures a CachingInventoryAdapter
+type CacheOptions struct {
+	TTL         time.Duration // how long a stock level is served from cache
+	NegativeTTL time.Duration // how long "not found" answers are cached; 0 disables negative caching
+	MaxEntries  int           // upper bound on cached entries; 0 means unbounded
+}
+
+// CacheStats is a snapshot of cache activity
+type CacheStats struct {
+	Hits         uint64 // lookups answered from a cached stock level
+	NegativeHits uint64 // lookups answered from a cached "not found"
+	Misses       uint64 // lookups that had to call the wrapped adapter
+	Coalesced    uint64 // misses that waited for an identical in-flight lookup
+	Evictions    uint64 // entries dropped to respect MaxEntries
+	Size         int    // entries currently cached
+}
+
+// cacheEntry is a cached answer from the wrapped adapter
+type cacheEntry struct {
+	key     stockKey
+	info    StockInfo
+	err     error // non-nil for negative entries
+	expires time.Time
+}
+
+// inflightLookup is a lookup in progress that concurrent misses wait on
+type inflightLookup struct {
+	done chan struct{}
+	info StockInfo
+	err  error
+}
+
+// CachingInventoryAdapter is a read-through cache in front of any
+// InventoryAdapter. Concurrent misses for the same product and warehouse
+// share a single call to the wrapped adapter, and the least recently used
+// entries are evicted once MaxEntries is reached.
+//
+// Only authoritative answers are cached: stock levels and "not found".
+// Unavailable sources and timeouts are always retried. A lookup is not
+// cancelled when the caller that started it gives up, so it can still answer
+// the others waiting on it; the wrapped adapter must bound its own calls, as
+// the API adapter does with its client timeout.
+type CachingInventoryAdapter struct {
+	inner   InventoryAdapter
+	options CacheOptions
+	clock   func() time.Time
+
+	mu       sync.Mutex
+	entries  map[stockKey]*list.Element
+	lru      *list.List // front = most recently used
+	inflight map[stockKey]*inflightLookup
+	stats    CacheStats
+	purges   uint64 // bumped by Purge so in-flight lookups started earlier are not stored
+}
+
+// NewCachingInventoryAdapter wraps an adapter with a read-through cache
+func NewCachingInventoryAdapter(inner InventoryAdapter, options CacheOptions) *CachingInventoryAdapter {
+	return &CachingInventoryAdapter{
+		inner:    inner,
+		options:  options,
+		clock:    now,
+		entries:  map[stockKey]*list.Element{},
+		lru:      list.New(),
+		inflight: map[stockKey]*inflightLookup{},
+	}
+}
+
+// LoadInventory reloads the wrapped adapter and drops every cached entry
+func (c *CachingInventoryAdapter) LoadInventory() error {
+	err := c.inner.LoadInventory()
+	if err != nil {
+		return err
+	}
+	c.Purge()
+	return nil
+}
+
+// Purge drops every cached entry
+func (c *CachingInventoryAdapter) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[stockKey]*list.Element{}
+	c.lru.Init()
+	c.purges++
+}
+
+// Stats returns a snapshot of the cache counters
+func (c *CachingInventoryAdapter) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.lru.Len()
+	return stats
+}
+
+// GetStockLevel retrieves the stock level, from cache when possible
+func (c *CachingInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	info, err := c.GetStockInfo(ctx, productID, warehouse)
+	return info.StockLevel, err
+}
+
+// GetStockInfo retrieves the stock level and its source, from cache when
+// possible, and notes on the current span whether the cache answered
+func (c *CachingInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	key := stockKey{productID, warehouse}
+	span := spanFromContext(ctx)
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		if c.clock().Before(entry.expires) {
+			c.lru.MoveToFront(elem)
+			if entry.err != nil {
+				c.stats.NegativeHits++
+			} else {
+				c.stats.Hits++
+			}
+			c.mu.Unlock()
+			span.SetAttributes(slog.String("cache", "hit"))
+			return entry.info, entry.err
+		}
+		c.removeLocked(elem)
+	}
+
+	if call, ok := c.inflight[key]; ok {
+		c.stats.Coalesced++
+		c.mu.Unlock()
+		span.SetAttributes(slog.String("cache", "coalesced"))
+		return call.wait(ctx)
+	}
+	c.stats.Misses++
+	span.SetAttributes(slog.String("cache", "miss"))
+
+	call := &inflightLookup{done: make(chan struct{})}
+	c.inflight[key] = call
+	purges := c.purges
+	c.mu.Unlock()
+
+	// The lookup is shared by everyone waiting on the key, so it runs
+	// detached from this caller's cancellation; each caller stops waiting
+	// when its own context is done
+	go func() {
+		var info StockInfo
+		var err error
+		// Waiters are released even if the lookup panics. Nothing above this
+		// goroutine could recover the panic, so it becomes an uncached error
+		defer func() {
+			if r := recover(); r != nil {
+				slog.Error("inventory lookup panicked", "product_id", productID, "warehouse", warehouse, "panic", r)
+				info, err = StockInfo{}, fmt.Errorf("%w: lookup panicked: %v", ErrSourceUnavailable, r)
+			}
+			c.mu.Lock()
+			call.info, call.err = info, err
+			delete(c.inflight, key)
+			if c.purges == purges {
+				c.storeLocked(key, info, err)
+			}
+			c.mu.Unlock()
+			close(call.done)
+		}()
+		info, err = lookupStock(context.WithoutCancel(ctx), c.inner, productID, warehouse)
+	}()
+
+	return call.wait(ctx)
+}
+
+// wait returns the lookup's answer, or gives up when ctx is done
+func (l *inflightLookup) wait(ctx context.Context) (StockInfo, error) {
+	select {
+	case <-l.done:
+		return l.info, l.err
+	case <-ctx.Done():
+		return StockInfo{}, contextError(ctx.Err())
+	}
+}
+
+// storeLocked caches an answer if it is cacheable. c.mu must be held
+func (c *CachingInventoryAdapter) storeLocked(key stockKey, info StockInfo, err error) {
+	ttl := c.options.TTL
+	if err != nil {
+		if !isAuthoritative(err) {
+			return
+		}
+		ttl = c.options.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.removeLocked(elem)
+	}
+	entry := &cacheEntry{key: key, info: info, err: err, expires: c.clock().Add(ttl)}
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.options.MaxEntries > 0 && c.lru.Len() > c.options.MaxEntries {
+		c.removeLocked(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+// removeLocked drops a cached entry. c.mu must be held
+func (c *CachingInventoryAdapter) removeLocked(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+}
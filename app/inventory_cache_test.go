@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// CountingInventoryAdapter wraps the mock adapter and counts lookups. When
+// gate is set, lookups block until it is closed; when panics is set, they
+// then panic
+type CountingInventoryAdapter struct {
+	*MockInventoryAdapter
+	calls  atomic.Int64
+	gate   chan struct{}
+	err    error
+	panics atomic.Bool
+}
+
+func (c *CountingInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	c.calls.Add(1)
+	if c.gate != nil {
+		<-c.gate
+	}
+	if c.panics.Load() {
+		panic("inventory index corrupted")
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.MockInventoryAdapter.GetStockLevel(ctx, productID, warehouse)
+}
+
+// newTestCache returns a cache over a counting adapter with a controllable clock
+func newTestCache(options CacheOptions) (*CachingInventoryAdapter, *CountingInventoryAdapter, *time.Time) {
+	inner := &CountingInventoryAdapter{MockInventoryAdapter: NewMockInventoryAdapter()}
+	cache := NewCachingInventoryAdapter(inner, options)
+	clock := now()
+	cache.clock = func() time.Time { return clock }
+	return cache, inner, &clock
+}
+
+func TestCachingAdapter_ServesFromCacheUntilTTL(t *testing.T) {
+	cache, inner, clock := newTestCache(CacheOptions{TTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		stock, err := cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+		if err != nil || stock != 100 {
+			t.Fatalf("Expected stock=100, got %d (err=%v)", stock, err)
+		}
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 upstream call within TTL, got %d", calls)
+	}
+
+	*clock = clock.Add(time.Minute)
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if calls := inner.calls.Load(); calls != 2 {
+		t.Errorf("Expected entry to expire after TTL, got %d upstream calls", calls)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Size != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestCachingAdapter_NegativeCaching(t *testing.T) {
+	cache, inner, clock := newTestCache(CacheOptions{TTL: time.Minute, NegativeTTL: 10 * time.Second})
+
+	cache.GetStockLevel(t.Context(), "PROD-999", "DE-Berlin")
+	_, err := cache.GetStockLevel(t.Context(), "PROD-999", "DE-Berlin")
+	if err == nil {
+		t.Fatal("Expected cached not-found error")
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("Expected not-found to be cached, got %d upstream calls", calls)
+	}
+	if stats := cache.Stats(); stats.NegativeHits != 1 {
+		t.Errorf("Expected 1 negative hit, got %+v", stats)
+	}
+
+	*clock = clock.Add(10 * time.Second)
+	cache.GetStockLevel(t.Context(), "PROD-999", "DE-Berlin")
+	if calls := inner.calls.Load(); calls != 2 {
+		t.Errorf("Expected negative entry to expire, got %d upstream calls", calls)
+	}
+}
+
+func TestCachingAdapter_DoesNotCacheFailures(t *testing.T) {
+	cache, inner, _ := newTestCache(CacheOptions{TTL: time.Minute, NegativeTTL: time.Minute})
+	inner.err = fmt.Errorf("%w: connection refused", ErrSourceUnavailable)
+
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if calls := inner.calls.Load(); calls != 2 {
+		t.Errorf("Expected unavailable answers to be retried, got %d upstream calls", calls)
+	}
+}
+
+func TestCachingAdapter_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache, inner, _ := newTestCache(CacheOptions{TTL: time.Minute, MaxEntries: 2})
+
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	cache.GetStockLevel(t.Context(), "PROD-456", "DE-Berlin")
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin") // PROD-456 is now least recently used
+	cache.GetStockLevel(t.Context(), "PROD-404", "DE-Berlin") // evicts PROD-456
+
+	if stats := cache.Stats(); stats.Evictions != 1 || stats.Size != 2 {
+		t.Errorf("Expected 1 eviction and 2 entries, got %+v", stats)
+	}
+
+	before := inner.calls.Load()
+	cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if inner.calls.Load() != before {
+		t.Error("Expected recently used entry to survive eviction")
+	}
+	cache.GetStockLevel(t.Context(), "PROD-456", "DE-Berlin")
+	if inner.calls.Load() != before+1 {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+}
+
+func TestCachingAdapter_CoalescesConcurrentMisses(t *testing.T) {
+	cache, inner, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	inner.gate = make(chan struct{})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+		}(i)
+	}
+
+	// Release the upstream call once every caller is waiting on it
+	for {
+		stats := cache.Stats()
+		if stats.Misses+stats.Coalesced == callers {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.gate)
+	wg.Wait()
+
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 upstream call for concurrent misses, got %d", calls)
+	}
+	for i, stock := range results {
+		if stock != 100 {
+			t.Errorf("Caller %d: expected stock=100, got %d", i, stock)
+		}
+	}
+}
+
+func TestCachingAdapter_WaiterGivesUpAtItsDeadline(t *testing.T) {
+	cache, inner, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	inner.gate = make(chan struct{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetStockLevel(ctx, "PROD-123", "DE-Berlin")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected ErrTimeout once the caller's deadline passed, got %v", err)
+	}
+
+	// The abandoned lookup still completes and answers later callers
+	close(inner.gate)
+	stock, err := cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if err != nil || stock != 100 {
+		t.Errorf("Expected stock=100, got %d (err=%v)", stock, err)
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Errorf("Expected the shared lookup to be reused, got %d upstream calls", calls)
+	}
+}
+
+func TestCachingAdapter_PanicReleasesWaiters(t *testing.T) {
+	cache, inner, _ := newTestCache(CacheOptions{TTL: time.Minute})
+	inner.gate = make(chan struct{})
+	inner.panics.Store(true)
+
+	const callers = 3
+	errs := make(chan error, callers)
+	for range callers {
+		go func() {
+			_, err := cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+			errs <- err
+		}()
+	}
+	for {
+		stats := cache.Stats()
+		if stats.Misses+stats.Coalesced == callers {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(inner.gate)
+
+	for range callers {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrSourceUnavailable) {
+				t.Errorf("Expected ErrSourceUnavailable, got %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Waiter still blocked after the lookup panicked")
+		}
+	}
+
+	// The failure is not cached
+	inner.panics.Store(false)
+	if stock, err := cache.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin"); err != nil || stock != 100 {
+		t.Errorf("Expected stock=100 on retry, got %d (err=%v)", stock, err)
+	}
+}
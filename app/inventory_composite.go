@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// InventorySource is a named adapter inside a CompositeInventoryAdapter
+type InventorySource struct {
+	Name    string
+	Adapter InventoryAdapter
+}
+
+// CompositeInventoryAdapter chains inventory sources in priority order, e.g.
+// the live API first and a last-known snapshot file as fallback.
+//
+// A source that answers "not found" or "unknown warehouse" is authoritative
+// and ends the lookup; only sources that fail to answer (unavailable, timeout
+// or any other error) fall through to the next one. A source that failed to
+// load is skipped until a later load succeeds, as its empty data would
+// otherwise answer "not found" for everything.
+type CompositeInventoryAdapter struct {
+	sources []InventorySource
+	failed  []atomic.Bool // by source index; set while the last load failed
+}
+
+// NewCompositeInventoryAdapter creates a composite adapter over the given sources
+func NewCompositeInventoryAdapter(sources ...InventorySource) *CompositeInventoryAdapter {
+	return &CompositeInventoryAdapter{
+		sources: sources,
+		failed:  make([]atomic.Bool, len(sources)),
+	}
+}
+
+// LoadInventory loads every source. It only fails if no source could be
+// loaded, so the service can start while the primary is down.
+func (c *CompositeInventoryAdapter) LoadInventory() error {
+	if len(c.sources) == 0 {
+		return fmt.Errorf("no inventory sources configured")
+	}
+
+	var errs []error
+	for i, source := range c.sources {
+		err := source.Adapter.LoadInventory()
+		c.failed[i].Store(err != nil)
+		if err != nil {
+			slog.Warn("inventory source failed to load", "source", source.Name, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name, err))
+		}
+	}
+
+	if len(errs) == len(c.sources) {
+		return fmt.Errorf("no inventory source could be loaded: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// GetStockLevel retrieves the stock level from the first source that can answer
+func (c *CompositeInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	info, err := c.GetStockInfo(ctx, productID, warehouse)
+	return info.StockLevel, err
+}
+
+// GetStockInfo retrieves the stock level from the first source that can
+// answer and reports that source's name. Sources without their own
+// timestamp are treated as live.
+func (c *CompositeInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	var errs []error
+	for i, source := range c.sources {
+		if c.failed[i].Load() {
+			errs = append(errs, fmt.Errorf("%s: %w: not loaded", source.Name, ErrSourceUnavailable))
+			continue
+		}
+		info, err := c.lookupSource(ctx, source, productID, warehouse)
+		if err == nil {
+			info.Source = source.Name
+			if info.AsOf.IsZero() {
+				info.AsOf = now()
+			}
+			return info, nil
+		}
+		if isAuthoritative(err) {
+			return StockInfo{}, err
+		}
+		// Out of time: trying the remaining sources would fail the same way
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return StockInfo{}, contextError(ctxErr)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", source.Name, err))
+	}
+
+	// Report a timeout only if every source timed out
+	kind := ErrTimeout
+	for _, err := range errs {
+		if !errors.Is(err, ErrTimeout) {
+			kind = ErrSourceUnavailable
+			break
+		}
+	}
+	return StockInfo{}, fmt.Errorf("%w: all sources failed: %w", kind, errors.Join(errs...))
+}
+
+// lookupSource asks one source for stock, traced as its own span
+func (c *CompositeInventoryAdapter) lookupSource(ctx context.Context, source InventorySource, productID, warehouse string) (StockInfo, error) {
+	ctx, span := startSpan(ctx, "inventory.source", SpanKindInternal, slog.String("source", source.Name))
+	defer span.End()
+	info, err := lookupStock(ctx, source.Adapter, productID, warehouse)
+	recordLookup(span, info, err)
+	return info, err
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// UnavailableInventoryAdapter is a test double for a source that is down
+type UnavailableInventoryAdapter struct{}
+
+func (u *UnavailableInventoryAdapter) LoadInventory() error {
+	return fmt.Errorf("%w: connection refused", ErrSourceUnavailable)
+}
+
+func (u *UnavailableInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	return 0, fmt.Errorf("%w: connection refused", ErrSourceUnavailable)
+}
+
+// SnapshotInventoryAdapter wraps the mock adapter and reports a fixed timestamp
+type SnapshotInventoryAdapter struct {
+	*MockInventoryAdapter
+	asOf time.Time
+}
+
+func (s *SnapshotInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	stock, err := s.GetStockLevel(ctx, productID, warehouse)
+	if err != nil {
+		return StockInfo{}, err
+	}
+	return StockInfo{StockLevel: stock, Source: "snapshot.json", AsOf: s.asOf}, nil
+}
+
+func TestCompositeAdapter_FallsBackWhenPrimaryUnavailable(t *testing.T) {
+	asOf := now().Add(-2 * time.Hour)
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+		InventorySource{Name: "snapshot", Adapter: &SnapshotInventoryAdapter{NewMockInventoryAdapter(), asOf}},
+	)
+
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("Expected load to succeed with one healthy source, got %v", err)
+	}
+
+	info, err := adapter.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.StockLevel != 100 || info.Source != "snapshot" || !info.AsOf.Equal(asOf) {
+		t.Errorf("Expected stock 100 from snapshot as of %v, got %+v", asOf, info)
+	}
+}
+
+func TestCompositeAdapter_NotFoundIsAuthoritative(t *testing.T) {
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "primary", Adapter: NewMockInventoryAdapter()},
+		InventorySource{Name: "fallback", Adapter: &UnavailableInventoryAdapter{}},
+	)
+
+	_, err := adapter.GetStockLevel(t.Context(), "PROD-999", "DE-Berlin")
+	if !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+}
+
+func TestCompositeAdapter_SkipsSourceThatFailedToLoad(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+		InventorySource{Name: "snapshot", Adapter: NewFileInventoryAdapter(snapshotPath)},
+		InventorySource{Name: "mock", Adapter: NewMockInventoryAdapter()},
+	)
+	adapter.LoadInventory()
+
+	// The snapshot has no data, so it must not answer "not found"
+	info, err := adapter.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+	if err != nil || info.Source != "mock" {
+		t.Fatalf("Expected the snapshot skipped in favour of mock, got %+v, %v", info, err)
+	}
+
+	// Once the snapshot loads it answers again
+	if err := os.WriteFile(snapshotPath, []byte(`[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 7}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	adapter.LoadInventory()
+	info, err = adapter.GetStockInfo(t.Context(), "PROD-123", "DE-Berlin")
+	if err != nil || info.Source != "snapshot" || info.StockLevel != 7 {
+		t.Errorf("Expected stock 7 from the reloaded snapshot, got %+v, %v", info, err)
+	}
+}
+
+func TestCompositeAdapter_UnloadedSnapshotIsNotAuthoritative(t *testing.T) {
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+		InventorySource{Name: "snapshot", Adapter: NewFileInventoryAdapter(filepath.Join(t.TempDir(), "missing.json"))},
+	)
+	adapter.LoadInventory()
+
+	_, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if !errors.Is(err, ErrSourceUnavailable) || errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected ErrSourceUnavailable, got %v", err)
+	}
+}
+
+func TestCompositeAdapter_AllSourcesUnavailable(t *testing.T) {
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+		InventorySource{Name: "replica", Adapter: &UnavailableInventoryAdapter{}},
+	)
+
+	if err := adapter.LoadInventory(); err == nil {
+		t.Error("Expected load to fail when every source fails")
+	}
+
+	_, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if !errors.Is(err, ErrSourceUnavailable) {
+		t.Errorf("Expected ErrSourceUnavailable, got %v", err)
+	}
+	if errors.Is(err, ErrProductNotFound) {
+		t.Error("Unavailable sources must not be reported as not found")
+	}
+}
+
+func TestCheckAvailability_ReportsSourceAndAge(t *testing.T) {
+	asOf := now().Add(-90 * time.Second)
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+		InventorySource{Name: "snapshot", Adapter: &SnapshotInventoryAdapter{NewMockInventoryAdapter(), asOf}},
+	)
+	service := NewAvailabilityService(adapter)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+
+	if !resp.Available {
+		t.Errorf("Expected available=true, got false. Reason: %s", resp.Reason)
+	}
+	if resp.Source == nil || resp.Source.Name != "snapshot" {
+		t.Fatalf("Expected source=snapshot, got %+v", resp.Source)
+	}
+	if resp.Source.AgeSeconds != 90 {
+		t.Errorf("Expected age_seconds=90, got %d", resp.Source.AgeSeconds)
+	}
+}
+
+func TestCheckAvailability_SourceUnavailable(t *testing.T) {
+	service := NewAvailabilityService(NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+	))
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available {
+		t.Error("Expected available=false when no source can answer")
+	}
+	if resp.Reason != "Inventory data temporarily unavailable" {
+		t.Errorf("Expected unavailable reason, got '%s'", resp.Reason)
+	}
+}
+
+// CancellingInventoryAdapter is a test double for a source that fails after
+// the caller gave up
+type CancellingInventoryAdapter struct {
+	cancel context.CancelFunc
+}
+
+func (c *CancellingInventoryAdapter) LoadInventory() error {
+	return nil
+}
+
+func (c *CancellingInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	c.cancel()
+	return 0, fmt.Errorf("%w: connection reset", ErrSourceUnavailable)
+}
+
+func TestCompositeAdapter_StopsWhenContextDone(t *testing.T) {
+	fallback := &CountingInventoryAdapter{MockInventoryAdapter: NewMockInventoryAdapter()}
+	ctx, cancel := context.WithCancel(t.Context())
+	adapter := NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &CancellingInventoryAdapter{cancel}},
+		InventorySource{Name: "snapshot", Adapter: fallback},
+	)
+
+	_, err := adapter.GetStockLevel(ctx, "PROD-123", "DE-Berlin")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancellation to be reported, got %v", err)
+	}
+	if calls := fallback.calls.Load(); calls != 0 {
+		t.Errorf("Expected the fallback not to be tried, got %d calls", calls)
+	}
+}
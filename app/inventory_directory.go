@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stockKey identifies a product at a warehouse
+type stockKey struct {
+	productID string
+	warehouse string
+}
+
+// InventoryConflict describes a (product, warehouse) pair that appears in
+// more than one row with different stock levels
+type InventoryConflict struct {
+	ProductID string
+	Warehouse string
+	Rows      []string // "file: stock_level" for every conflicting row
+}
+
+// ConflictError is returned when inventory files disagree about a stock level
+type ConflictError struct {
+	Conflicts []InventoryConflict
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		parts = append(parts, fmt.Sprintf("%s@%s (%s)", c.ProductID, c.Warehouse, strings.Join(c.Rows, ", ")))
+	}
+	return fmt.Sprintf("conflicting inventory rows: %s", strings.Join(parts, "; "))
+}
+
+// inventoryFile holds the parsed contents of one file in the inventory directory
+type inventoryFile struct {
+	modTime time.Time
+	size    int64
+	items   []InventoryItem
+}
+
+// DirectoryInventoryAdapter implements InventoryAdapter by merging every
+// inventory file in a directory into one view. Each warehouse team owns its
+// own export (e.g. DE-Berlin.json, US-NewYork.csv); rows that leave the
+// warehouse empty take it from the file name.
+type DirectoryInventoryAdapter struct {
+	dirPath string
+
+	mu         sync.RWMutex
+	files      map[string]*inventoryFile // file name -> parsed contents
+	index      map[stockKey]stockEntry   // merged stock levels
+	warehouses map[string]bool           // warehouses with at least one row
+}
+
+// stockEntry is a merged stock level and the file it came from
+type stockEntry struct {
+	stock int
+	lots  []Lot
+	file  string
+}
+
+// NewDirectoryInventoryAdapter creates a new directory-based inventory adapter
+func NewDirectoryInventoryAdapter(dirPath string) *DirectoryInventoryAdapter {
+	return &DirectoryInventoryAdapter{
+		dirPath:    dirPath,
+		files:      map[string]*inventoryFile{},
+		index:      map[stockKey]stockEntry{},
+		warehouses: map[string]bool{},
+	}
+}
+
+// LoadInventory reads every .json and .csv file in the directory and merges
+// them. If any rows conflict the previous inventory is kept and a
+// *ConflictError is returned.
+func (d *DirectoryInventoryAdapter) LoadInventory() error {
+	entries, err := d.listFiles()
+	if err != nil {
+		return err
+	}
+
+	files := make(map[string]*inventoryFile, len(entries))
+	for name, info := range entries {
+		file, err := d.readFile(name, info)
+		if err != nil {
+			return err
+		}
+		files[name] = file
+	}
+
+	return d.swap(files)
+}
+
+// ReloadChanged re-reads files that were added, modified or removed since the
+// last load and returns their names. Unchanged files are not parsed again.
+func (d *DirectoryInventoryAdapter) ReloadChanged() ([]string, error) {
+	entries, err := d.listFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.RLock()
+	files := make(map[string]*inventoryFile, len(entries))
+	var changed []string
+	for name, info := range entries {
+		current, ok := d.files[name]
+		if ok && current.modTime.Equal(info.ModTime()) && current.size == info.Size() {
+			files[name] = current
+			continue
+		}
+		changed = append(changed, name)
+	}
+	for name := range d.files {
+		if _, ok := entries[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	d.mu.RUnlock()
+
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	sort.Strings(changed)
+
+	for _, name := range changed {
+		info, ok := entries[name]
+		if !ok {
+			continue // removed
+		}
+		file, err := d.readFile(name, info)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = file
+	}
+
+	if err := d.swap(files); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// Size returns the number of merged product and warehouse rows
+func (d *DirectoryInventoryAdapter) Size() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.index)
+}
+
+// AsOf returns the modification time of the oldest loaded file, as the
+// merged view is only as fresh as its stalest export
+func (d *DirectoryInventoryAdapter) AsOf() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var oldest time.Time
+	for _, file := range d.files {
+		if oldest.IsZero() || file.modTime.Before(oldest) {
+			oldest = file.modTime
+		}
+	}
+	return oldest
+}
+
+// Watch polls the directory every interval and reloads changed files until
+// the returned stop function is called. onReload, if set, is told the
+// outcome of every poll and which files it reloaded
+func (d *DirectoryInventoryAdapter) Watch(interval time.Duration, onReload func(changed []string, err error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				changed, err := d.ReloadChanged()
+				if onReload != nil {
+					onReload(changed, err)
+				}
+				if err != nil {
+					slog.Error("inventory reload failed", "dir", d.dirPath, "error", err)
+					continue
+				}
+				if len(changed) > 0 {
+					slog.Info("inventory reloaded", "dir", d.dirPath, "files", changed)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// GetStockLevel retrieves the stock level for a product at a specific warehouse
+func (d *DirectoryInventoryAdapter) GetStockLevel(ctx context.Context, productID, warehouse string) (int, error) {
+	info, err := d.GetStockInfo(ctx, productID, warehouse)
+	return info.StockLevel, err
+}
+
+// GetStockInfo retrieves the stock level along with the file that supplied it.
+// The file's modification time is reported as the data's age
+func (d *DirectoryInventoryAdapter) GetStockInfo(ctx context.Context, productID, warehouse string) (StockInfo, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.index[stockKey{productID, warehouse}]
+	if !ok {
+		if !d.warehouses[warehouse] {
+			return StockInfo{}, fmt.Errorf("%w: %s", ErrUnknownWarehouse, warehouse)
+		}
+		return StockInfo{}, fmt.Errorf("%w: %s in warehouse %s", ErrProductNotFound, productID, warehouse)
+	}
+	return StockInfo{
+		StockLevel: entry.stock,
+		Lots:       entry.lots,
+		Source:     filepath.Join(d.dirPath, entry.file),
+		AsOf:       d.files[entry.file].modTime,
+	}, nil
+}
+
+// listFiles returns the inventory files in the directory keyed by name
+func (d *DirectoryInventoryAdapter) listFiles() (map[string]os.FileInfo, error) {
+	entries, err := os.ReadDir(d.dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory directory: %w", err)
+	}
+
+	files := map[string]os.FileInfo{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".json" && ext != ".csv" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat inventory file %s: %w", name, err)
+		}
+		files[name] = info
+	}
+	return files, nil
+}
+
+// readFile parses a single inventory file
+func (d *DirectoryInventoryAdapter) readFile(name string, info os.FileInfo) (*inventoryFile, error) {
+	f, err := os.Open(filepath.Join(d.dirPath, name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open inventory file %s: %w", name, err)
+	}
+	defer f.Close()
+
+	var items []InventoryItem
+	if strings.ToLower(filepath.Ext(name)) == ".csv" {
+		items, err = parseInventoryCSV(f)
+	} else {
+		err = json.NewDecoder(f).Decode(&items)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse inventory file %s: %w", name, err)
+	}
+
+	// Rows without a warehouse belong to the warehouse named by the file
+	defaultWarehouse := strings.TrimSuffix(name, filepath.Ext(name))
+	for i := range items {
+		if items[i].Warehouse == "" {
+			items[i].Warehouse = defaultWarehouse
+		}
+		if err := validateLots(items[i]); err != nil {
+			return nil, fmt.Errorf("inventory file %s: %w", name, err)
+		}
+	}
+
+	return &inventoryFile{
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		items:   items,
+	}, nil
+}
+
+// swap merges the given files and, if they do not conflict, replaces the
+// current inventory with them
+func (d *DirectoryInventoryAdapter) swap(files map[string]*inventoryFile) error {
+	index, err := mergeInventoryFiles(files)
+	if err != nil {
+		return err
+	}
+
+	warehouses := map[string]bool{}
+	for key := range index {
+		warehouses[key.warehouse] = true
+	}
+
+	d.mu.Lock()
+	d.files = files
+	d.index = index
+	d.warehouses = warehouses
+	d.mu.Unlock()
+	return nil
+}
+
+// mergeInventoryFiles builds the merged stock index. Duplicate rows with the
+// same stock level are tolerated; differing ones are reported as conflicts.
+func mergeInventoryFiles(files map[string]*inventoryFile) (map[stockKey]stockEntry, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	index := map[stockKey]stockEntry{}
+	rows := map[stockKey][]string{}
+	conflicting := map[stockKey]bool{}
+	var order []stockKey
+
+	for _, name := range names {
+		for _, item := range files[name].items {
+			key := stockKey{item.ProductID, item.Warehouse}
+			if existing, ok := index[key]; ok && existing.stock != item.StockLevel && !conflicting[key] {
+				conflicting[key] = true
+				order = append(order, key)
+			}
+			index[key] = stockEntry{stock: item.StockLevel, lots: item.Lots, file: name}
+			rows[key] = append(rows[key], fmt.Sprintf("%s: %d", name, item.StockLevel))
+		}
+	}
+
+	if len(order) == 0 {
+		return index, nil
+	}
+
+	conflicts := make([]InventoryConflict, 0, len(order))
+	for _, key := range order {
+		conflicts = append(conflicts, InventoryConflict{
+			ProductID: key.productID,
+			Warehouse: key.warehouse,
+			Rows:      rows[key],
+		})
+	}
+	return nil, &ConflictError{Conflicts: conflicts}
+}
+
+// parseInventoryCSV reads inventory rows from CSV. The header must contain
+// product_id and stock_level; the warehouse column is optional.
+func parseInventoryCSV(r io.Reader) ([]InventoryItem, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	productCol, ok := columns["product_id"]
+	if !ok {
+		return nil, fmt.Errorf("missing product_id column")
+	}
+	stockCol, ok := columns["stock_level"]
+	if !ok {
+		return nil, fmt.Errorf("missing stock_level column")
+	}
+	warehouseCol, hasWarehouse := columns["warehouse"]
+
+	var items []InventoryItem
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		stock, err := strconv.Atoi(strings.TrimSpace(record[stockCol]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid stock_level %q", line, record[stockCol])
+		}
+
+		item := InventoryItem{
+			ProductID:  strings.TrimSpace(record[productCol]),
+			StockLevel: stock,
+		}
+		if hasWarehouse {
+			item.Warehouse = strings.TrimSpace(record[warehouseCol])
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
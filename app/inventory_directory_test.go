@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeInventoryFile writes a file into dir and sets its modification time
+func writeInventoryFile(t *testing.T, dir, name, content string, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set mtime on %s: %v", name, err)
+	}
+}
+
+func TestDirectoryAdapter_MergesJSONAndCSV(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	writeInventoryFile(t, dir, "DE-Berlin.json", `[
+		{"product_id": "PROD-123", "stock_level": 100},
+		{"product_id": "PROD-456", "warehouse": "DE-Berlin", "stock_level": 25}
+	]`, base)
+	writeInventoryFile(t, dir, "US-NewYork.csv", "product_id,stock_level\nPROD-123,50\nPROD-789,15\n", base)
+	writeInventoryFile(t, dir, "README.md", "ignored", base)
+
+	adapter := NewDirectoryInventoryAdapter(dir)
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+
+	tests := []struct {
+		productID string
+		warehouse string
+		expected  int
+	}{
+		{"PROD-123", "DE-Berlin", 100},
+		{"PROD-456", "DE-Berlin", 25},
+		{"PROD-123", "US-NewYork", 50},
+		{"PROD-789", "US-NewYork", 15},
+	}
+	for _, tt := range tests {
+		stock, err := adapter.GetStockLevel(t.Context(), tt.productID, tt.warehouse)
+		if err != nil {
+			t.Errorf("Product %s in %s: unexpected error: %v", tt.productID, tt.warehouse, err)
+			continue
+		}
+		if stock != tt.expected {
+			t.Errorf("Product %s in %s: expected stock=%d, got %d", tt.productID, tt.warehouse, tt.expected, stock)
+		}
+	}
+
+	if _, err := adapter.GetStockLevel(t.Context(), "PROD-789", "DE-Berlin"); err == nil {
+		t.Error("Expected error for product missing from warehouse")
+	}
+}
+
+func TestDirectoryAdapter_DetectsConflicts(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	writeInventoryFile(t, dir, "DE-Berlin.json", `[{"product_id": "PROD-123", "stock_level": 100}]`, base)
+	writeInventoryFile(t, dir, "shared.csv", "product_id,warehouse,stock_level\nPROD-123,DE-Berlin,90\n", base)
+
+	adapter := NewDirectoryInventoryAdapter(dir)
+	err := adapter.LoadInventory()
+
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Expected *ConflictError, got %v", err)
+	}
+	if len(conflictErr.Conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(conflictErr.Conflicts))
+	}
+	if c := conflictErr.Conflicts[0]; c.ProductID != "PROD-123" || c.Warehouse != "DE-Berlin" || len(c.Rows) != 2 {
+		t.Errorf("Unexpected conflict: %+v", c)
+	}
+}
+
+func TestDirectoryAdapter_IdenticalDuplicatesAllowed(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	writeInventoryFile(t, dir, "DE-Berlin.json", `[{"product_id": "PROD-123", "stock_level": 100}]`, base)
+	writeInventoryFile(t, dir, "shared.csv", "product_id,warehouse,stock_level\nPROD-123,DE-Berlin,100\n", base)
+
+	adapter := NewDirectoryInventoryAdapter(dir)
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("Expected identical duplicates to load, got %v", err)
+	}
+}
+
+func TestDirectoryAdapter_ReloadChanged(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Now().Add(-time.Hour)
+	writeInventoryFile(t, dir, "DE-Berlin.json", `[{"product_id": "PROD-123", "stock_level": 100}]`, base)
+	writeInventoryFile(t, dir, "US-NewYork.json", `[{"product_id": "PROD-123", "stock_level": 50}]`, base)
+
+	adapter := NewDirectoryInventoryAdapter(dir)
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+
+	changed, err := adapter.ReloadChanged()
+	if err != nil || len(changed) != 0 {
+		t.Fatalf("Expected no changes, got %v (err=%v)", changed, err)
+	}
+
+	writeInventoryFile(t, dir, "DE-Berlin.json", `[{"product_id": "PROD-123", "stock_level": 40}]`, base.Add(time.Minute))
+	changed, err = adapter.ReloadChanged()
+	if err != nil {
+		t.Fatalf("ReloadChanged failed: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "DE-Berlin.json" {
+		t.Errorf("Expected only DE-Berlin.json to change, got %v", changed)
+	}
+	if stock, _ := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin"); stock != 40 {
+		t.Errorf("Expected reloaded stock=40, got %d", stock)
+	}
+
+	// A conflicting update is rejected and the previous inventory kept
+	writeInventoryFile(t, dir, "US-NewYork.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 1}]`, base.Add(2*time.Minute))
+	if _, err := adapter.ReloadChanged(); err == nil {
+		t.Error("Expected conflict error on reload")
+	}
+	if stock, _ := adapter.GetStockLevel(t.Context(), "PROD-123", "US-NewYork"); stock != 50 {
+		t.Errorf("Expected previous stock=50 to be kept, got %d", stock)
+	}
+
+	// Removed files drop out of the merged view
+	if err := os.Remove(filepath.Join(dir, "US-NewYork.json")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	if _, err := adapter.ReloadChanged(); err != nil {
+		t.Fatalf("ReloadChanged failed: %v", err)
+	}
+	if _, err := adapter.GetStockLevel(t.Context(), "PROD-123", "US-NewYork"); err == nil {
+		t.Error("Expected removed warehouse file to be dropped")
+	}
+}
+
+func TestDataset_WatcherReloadPurgesCache(t *testing.T) {
+	root := t.TempDir()
+	writeTenantDir(t, root, "retail", `[]`)
+	dir := filepath.Join(root, "retail")
+	if err := os.Mkdir(filepath.Join(dir, "inventory.d"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	modTime := now().Add(-time.Hour)
+	writeInventoryFile(t, filepath.Join(dir, "inventory.d"), "berlin.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 100}]`, modTime)
+
+	inventory := DefaultConfig().Inventory
+	inventory.CacheTTL = Duration(time.Hour)
+	inventory.WatchInterval = Duration(10 * time.Millisecond)
+	dataset, err := LoadDataset(dir, inventory)
+	if err != nil {
+		t.Fatalf("Failed to load dataset: %v", err)
+	}
+	t.Cleanup(dataset.Close)
+	if stock, _ := dataset.Inventory.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin"); stock != 100 {
+		t.Fatalf("Expected stock 100, got %d", stock)
+	}
+
+	writeInventoryFile(t, filepath.Join(dir, "inventory.d"), "berlin.json", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 3}]`, modTime.Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stock, _ := dataset.Inventory.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+		if stock == 3 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the reloaded stock within the cache TTL, still got %d", stock)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFileAdapter_DistinguishesUnknownWarehouse(t *testing.T) {
+	adapter := NewFileInventoryAdapter("inventory.json")
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+
+	if _, err := adapter.GetStockLevel(t.Context(), "PROD-999", "DE-Berlin"); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("Expected ErrProductNotFound, got %v", err)
+	}
+	if _, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-berlin"); !errors.Is(err, ErrUnknownWarehouse) {
+		t.Errorf("Expected ErrUnknownWarehouse, got %v", err)
+	}
+}
+
+// newInventoryAPI starts a fake inventory API for the given handler
+func newInventoryAPI(t *testing.T, handler http.HandlerFunc) *APIInventoryAdapter {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	adapter := NewAPIInventoryAdapter(server.URL)
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+	return adapter
+}
+
+func TestAPIAdapter_ReturnsStockLevel(t *testing.T) {
+	adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inventory" || r.URL.Query().Get("product") != "PROD-123" || r.URL.Query().Get("warehouse") != "DE-Berlin" {
+			t.Errorf("Unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 42}`))
+	})
+
+	stock, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stock != 42 {
+		t.Errorf("Expected stock=42, got %d", stock)
+	}
+}
+
+func TestAPIAdapter_MapsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected error
+	}{
+		{"product not found", http.StatusNotFound, `{"code": "product_not_found"}`, ErrProductNotFound},
+		{"unknown warehouse", http.StatusNotFound, `{"code": "unknown_warehouse"}`, ErrUnknownWarehouse},
+		{"server error", http.StatusInternalServerError, "", ErrSourceUnavailable},
+		{"gateway timeout", http.StatusGatewayTimeout, "", ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		})
+
+		_, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, err)
+		}
+	}
+}
+
+func TestAPIAdapter_ClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer close(release)
+	adapter.client.Timeout = 20 * time.Millisecond
+
+	if _, err := adapter.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin"); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestAPIAdapter_HonoursContextDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := adapter.GetStockLevel(ctx, "PROD-123", "DE-Berlin")
+
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTimeout from the context deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the call to stop at the deadline, took %s", elapsed)
+	}
+}
+
+func TestAPIAdapter_ForwardsRequestID(t *testing.T) {
+	var got string
+	adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("X-Request-ID")
+		w.Write([]byte(`{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 42}`))
+	})
+
+	adapter.GetStockLevel(withRequestID(t.Context(), "req-7"), "PROD-123", "DE-Berlin")
+	if got != "req-7" {
+		t.Errorf("Expected X-Request-ID req-7 on the outbound call, got %q", got)
+	}
+}
+
+func TestLookupStock_SkipsAdapterWhenContextDone(t *testing.T) {
+	inner := &CountingInventoryAdapter{MockInventoryAdapter: NewMockInventoryAdapter()}
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := lookupStock(ctx, inner, "PROD-123", "DE-Berlin")
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected ErrTimeout wrapping context.Canceled, got %v", err)
+	}
+	if calls := inner.calls.Load(); calls != 0 {
+		t.Errorf("Expected no adapter call, got %d", calls)
+	}
+}
+
+func TestAPIAdapter_PropagatesTraceContext(t *testing.T) {
+	var traceparent string
+	adapter := newInventoryAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 42}`))
+	})
+	exporter := &InMemoryExporter{}
+	ctx, root := NewTracer(exporter).Start(t.Context(), "test", SpanKindServer, SpanContext{})
+
+	adapter.GetStockLevel(ctx, "PROD-123", "DE-Berlin")
+	root.End()
+
+	client := findSpan(t, exporter.Spans(), "GET /inventory")
+	if client.Kind != SpanKindClient || client.Parent != root.SpanID {
+		t.Errorf("Expected a client span under the root, got %+v", client)
+	}
+	remote, ok := parseTraceparent(traceparent)
+	if !ok || remote.TraceID != root.TraceID || remote.SpanID != client.SpanID || !remote.Sampled {
+		t.Errorf("Expected the outbound call to carry the client span's context, got %q", traceparent)
+	}
+	if got := spanAttr(client, "http.response.status_code").Int64(); got != http.StatusOK {
+		t.Errorf("Expected status code 200 on the span, got %d", got)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// LogConfig selects the log level and format
+type LogConfig struct {
+	Level  string `json:"level"`  // debug, info, warn or error
+	Format string `json:"format"` // json or text
+}
+
+// newLogger creates the service logger writing to w. Records logged with a
+// request's context carry its request ID and, when traced, its trace and span IDs
+func newLogger(w io.Writer, cfg LogConfig) (*slog.Logger, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(cfg.Level))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch cfg.Format {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case LogFormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want json or text)", cfg.Format)
+	}
+	return slog.New(contextHandler{handler}), nil
+}
+
+// contextHandler adds the request ID and trace carried by a record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := requestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if span := spanFromContext(ctx); span != nil {
+		record.AddAttrs(slog.String("trace_id", span.TraceID.String()), slog.String("span_id", span.SpanID.String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// requestIDKey and logAttrsKey are the context keys for request logging
+type (
+	requestIDKey struct{}
+	logAttrsKey  struct{}
+)
+
+// withRequestID returns a context carrying the request ID
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// requestIDFromContext returns the request ID carried by ctx, or ""
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// logAttrs collects attributes handlers add to their request's access log line
+type logAttrs struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// addLogAttrs adds attributes, such as the outcome, to the access log line of
+// the request ctx belongs to. It does nothing outside a logged request
+func addLogAttrs(ctx context.Context, attrs ...slog.Attr) {
+	if collected, ok := ctx.Value(logAttrsKey{}).(*logAttrs); ok {
+		collected.mu.Lock()
+		collected.attrs = append(collected.attrs, attrs...)
+		collected.mu.Unlock()
+	}
+}
+
+// quietRoutes are polled by probes and scrapers; their access lines are debug level
+var quietRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// logRequests gives every request an ID and writes an access log line when
+// it completes. The ID is taken from a well-formed X-Request-ID header or
+// generated, and echoed in the response's X-Request-ID header
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+
+		collected := &logAttrs{}
+		ctx := withRequestID(r.Context(), id)
+		ctx = context.WithValue(ctx, logAttrsKey{}, collected)
+		r = r.WithContext(ctx)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		level := slog.LevelInfo
+		switch {
+		case recorder.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case quietRoutes[routeFromContext(ctx)]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", routeFromContext(ctx)),
+			slog.Int("status", recorder.status),
+			slog.Int64("bytes", recorder.bytes),
+			slog.Float64("duration_ms", durationMillis(time.Since(start))),
+			slog.String("remote_addr", r.RemoteAddr),
+		}
+		collected.mu.Lock()
+		attrs = append(attrs, collected.attrs...)
+		collected.mu.Unlock()
+		slog.LogAttrs(ctx, level, "request", attrs...)
+	})
+}
+
+// validRequestID accepts client-supplied IDs of up to 128 characters from a
+// conservative set, so they are safe to log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	return strings.IndexFunc(id, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.:", r))
+	}) < 0
+}
+
+// newRequestID returns a random 128-bit ID in hex
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// durationMillis converts a duration to fractional milliseconds for logging
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// captureLogs routes the default logger to a buffer of JSON records at debug
+// level for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, LogConfig{Level: "debug", Format: LogFormatJSON})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// logRecords decodes the captured JSON records with the given message
+func logRecords(t *testing.T, buf *bytes.Buffer, msg string) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+		if record["msg"] == msg {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func TestNewLogger_RejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  LogConfig
+	}{
+		{"unknown level", LogConfig{Level: "verbose", Format: LogFormatJSON}},
+		{"unknown format", LogConfig{Level: "info", Format: "xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newLogger(&bytes.Buffer{}, tt.cfg); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNewLogger_AddsRequestIDFromContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger, _ := newLogger(&buf, LogConfig{Level: "info", Format: LogFormatText})
+
+	logger.InfoContext(withRequestID(t.Context(), "req-1"), "hello", "warehouse", "DE-Berlin")
+	logger.Info("no request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], "request_id=req-1") || !strings.Contains(lines[0], "warehouse=DE-Berlin") {
+		t.Errorf("Expected the request ID on the first line, got %q", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("Expected no request ID without a request context, got %q", lines[1])
+	}
+}
+
+func TestNewLogger_AddsTraceFromContext(t *testing.T) {
+	buf := captureLogs(t)
+	ctx, span := NewTracer(&InMemoryExporter{}).Start(t.Context(), "test", SpanKindInternal, SpanContext{})
+
+	slog.InfoContext(ctx, "traced")
+
+	records := logRecords(t, buf, "traced")
+	if len(records) != 1 || records[0]["trace_id"] != span.TraceID.String() || records[0]["span_id"] != span.SpanID.String() {
+		t.Errorf("Expected the trace and span IDs on the record, got %v", records)
+	}
+}
+
+func TestLogRequests_RequestID(t *testing.T) {
+	captureLogs(t)
+	var seen string
+	handler := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{"accepted from the client", "checkout-7f3a.1", true},
+		{"generated when missing", "", false},
+		{"generated when malformed", "bad id\nwith newline", false},
+		{"generated when too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			echoed := rr.Header().Get("X-Request-ID")
+			if echoed != seen {
+				t.Errorf("Expected the response to echo %q, got %q", seen, echoed)
+			}
+			if tt.wantSame && seen != tt.header {
+				t.Errorf("Expected request ID %q, got %q", tt.header, seen)
+			}
+			if !tt.wantSame && (seen == tt.header || len(seen) != 32) {
+				t.Errorf("Expected a generated 32-character ID, got %q", seen)
+			}
+		})
+	}
+}
+
+func TestLogRequests_AccessLogWithOutcome(t *testing.T) {
+	buf := captureLogs(t)
+	mux := http.NewServeMux()
+	handler := NewAvailabilityHandler(NewAvailabilityService(NewMockInventoryAdapter()), nil)
+	mux.HandleFunc("/api/check-availability", handler.HandleCheckAvailability)
+
+	body := `{"product_id": "PROD-789", "quantity": 1, "warehouse_location": "DE-Berlin"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+	req.Header.Set("X-Request-ID", "req-42")
+	withRoute(mux, logRequests(mux)).ServeHTTP(httptest.NewRecorder(), req)
+
+	records := logRecords(t, buf, "request")
+	if len(records) != 1 {
+		t.Fatalf("Expected one access log line, got %d:\n%s", len(records), buf.String())
+	}
+	want := map[string]any{
+		"level":       "INFO",
+		"request_id":  "req-42",
+		"method":      "POST",
+		"route":       "/api/check-availability",
+		"status":      float64(http.StatusOK),
+		"product_id":  "PROD-789",
+		"warehouse":   "DE-Berlin",
+		"outcome":     StatusUnavailable,
+		"reason_code": ReasonOutOfStock,
+	}
+	for key, value := range want {
+		if records[0][key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, records[0][key])
+		}
+	}
+	if _, ok := records[0]["duration_ms"].(float64); !ok {
+		t.Errorf("Expected a duration_ms, got %v", records[0]["duration_ms"])
+	}
+
+	// The service's own records for the check carry the same request ID
+	decided := logRecords(t, buf, "availability decided")
+	if len(decided) != 1 || decided[0]["request_id"] != "req-42" {
+		t.Errorf("Expected the decision logged with the request ID, got %v", decided)
+	}
+}
+
+func TestLogRequests_ServerErrorsLoggedAsErrors(t *testing.T) {
+	buf := captureLogs(t)
+	handler := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/warehouses", nil))
+
+	records := logRecords(t, buf, "request")
+	if len(records) != 1 || records[0]["level"] != "ERROR" {
+		t.Errorf("Expected one ERROR access line, got %v", records)
+	}
+}
+
+func TestAvailabilityService_LogsFailedLookups(t *testing.T) {
+	buf := captureLogs(t)
+	service := NewAvailabilityService(&ErrorInventoryAdapter{err: fmt.Errorf("%w: connection refused", ErrSourceUnavailable)})
+
+	service.CheckAvailability(withRequestID(t.Context(), "req-9"), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+
+	records := logRecords(t, buf, "inventory lookup failed")
+	if len(records) != 1 {
+		t.Fatalf("Expected one failed lookup record, got %d:\n%s", len(records), buf.String())
+	}
+	if records[0]["level"] != "WARN" || records[0]["request_id"] != "req-9" || records[0]["product_id"] != "PROD-123" {
+		t.Errorf("Unexpected record %v", records[0])
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// validateLots checks that an item's lots have valid expiry dates and add up
+// to its stock level. Items without lots are not tracked by lot
+func validateLots(item InventoryItem) error {
+	if len(item.Lots) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, lot := range item.Lots {
+		if lot.Quantity < 0 {
+			return fmt.Errorf("%s@%s: lot %s has a negative quantity", item.ProductID, item.Warehouse, lot.LotNumber)
+		}
+		if _, err := time.Parse(time.DateOnly, lot.ExpiryDate); err != nil {
+			return fmt.Errorf("%s@%s: lot %s has invalid expiry_date %q (want YYYY-MM-DD)", item.ProductID, item.Warehouse, lot.LotNumber, lot.ExpiryDate)
+		}
+		total += lot.Quantity
+	}
+	if total != item.StockLevel {
+		return fmt.Errorf("%s@%s: lots add up to %d, stock_level is %d", item.ProductID, item.Warehouse, total, item.StockLevel)
+	}
+	return nil
+}
+
+// splitLotsByExpiry returns the quantity in lots that expire after the cutoff
+// and the short-dated quantity in lots that expire on or before it. Lots with
+// an unreadable expiry date count as short-dated
+func splitLotsByExpiry(lots []Lot, cutoff time.Time) (usable, shortDated int) {
+	for _, lot := range lots {
+		expiry, err := time.Parse(time.DateOnly, lot.ExpiryDate)
+		if err == nil && expiry.After(cutoff) {
+			usable += lot.Quantity
+		} else {
+			shortDated += lot.Quantity
+		}
+	}
+	return usable, shortDated
+}
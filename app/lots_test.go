@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newLotService returns a service over a file inventory holding two lots of
+// PROD-123 in DE-Berlin: 30 units expiring 2024-01-20 and 70 expiring 2024-06-30
+func newLotService(t *testing.T, options ...ServiceOption) *AvailabilityService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	os.WriteFile(path, []byte(`[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 100, "lots": [
+		{"lot_number": "A", "quantity": 30, "expiry_date": "2024-01-20"},
+		{"lot_number": "B", "quantity": 70, "expiry_date": "2024-06-30"}
+	]}]`), 0o644)
+
+	adapter := NewFileInventoryAdapter(path)
+	if err := adapter.LoadInventory(); err != nil {
+		t.Fatalf("LoadInventory failed: %v", err)
+	}
+	return NewAvailabilityService(adapter, options...)
+}
+
+func TestCheckAvailability_ExcludesShortDatedLots(t *testing.T) {
+	// Today is 2024-01-10; with 14 days minimum shelf life lot A is short-dated
+	service := newLotService(t, WithMinShelfLife(14))
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 60, WarehouseLocation: "DE-Berlin"})
+	if !resp.Available || resp.AvailableQuantity != 63 {
+		t.Errorf("Expected 63 available from lot B, got %+v", resp)
+	}
+	if resp.ShortDatedQuantity != 30 {
+		t.Errorf("Expected short_dated_quantity=30, got %d", resp.ShortDatedQuantity)
+	}
+
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 65, WarehouseLocation: "DE-Berlin"})
+	if resp.Available || resp.ReasonCode != ReasonInsufficientStock {
+		t.Errorf("Expected short-dated stock not to count, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_LotsWithoutMinShelfLife(t *testing.T) {
+	service := newLotService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 90, WarehouseLocation: "DE-Berlin"})
+	if !resp.Available || resp.ShortDatedQuantity != 0 {
+		t.Errorf("Expected both lots to count, got %+v", resp)
+	}
+}
+
+func TestCheckAvailability_LotExpiryUsesShipDate(t *testing.T) {
+	service := newLotService(t, WithMinShelfLife(14))
+
+	// Shipping 2024-06-20 needs lots good until after 2024-07-04
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: "DE-Berlin", ShipDate: "2024-06-20"})
+	if resp.Available || resp.ReasonCode != ReasonOutOfStock {
+		t.Errorf("Expected no lot to last long enough, got %+v", resp)
+	}
+	if resp.ShortDatedQuantity != 100 {
+		t.Errorf("Expected short_dated_quantity=100, got %d", resp.ShortDatedQuantity)
+	}
+}
+
+func TestFileAdapter_RejectsLotsNotMatchingStock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.json")
+	os.WriteFile(path, []byte(`[{"product_id": "PROD-1", "warehouse": "DE-Berlin", "stock_level": 10, "lots": [
+		{"lot_number": "A", "quantity": 4, "expiry_date": "2024-01-20"}
+	]}]`), 0o644)
+
+	if err := NewFileInventoryAdapter(path).LoadInventory(); err == nil {
+		t.Error("Expected lots that do not add up to stock_level to be rejected")
+	}
+}
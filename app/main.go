@@ -1,55 +1,233 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // warehouse time zones must resolve in minimal containers
 )
 
 func main() {
-	// Initialize the inventory adapter (using file-based adapter)
-	// This can be easily swapped with APIInventoryAdapter in the future
-	inventoryAdapter := NewFileInventoryAdapter("inventory.json")
+	// Layered configuration: defaults, config file, environment, flags
+	cfg, printConfig, err := LoadConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	if printConfig {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(cfg)
+		return
+	}
+
+	// Structured logs go to stderr; the standard log package is routed
+	// through the same handler
+	logger, err := newLogger(os.Stderr, cfg.Log)
+	if err != nil {
+		fatal("invalid configuration", err)
+	}
+	slog.SetDefault(logger)
+
+	// Traces are exported over OTLP when an endpoint is configured
+	var tracer *Tracer
+	if cfg.Tracing.Endpoint != "" {
+		tracer = NewTracer(NewOTLPExporter(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, time.Duration(cfg.Tracing.ExportInterval)))
+	}
 
-	// Load inventory data from JSON file
-	err := inventoryAdapter.LoadInventory()
+	// With authentication enabled the API and metrics need credentials;
+	// docs and probes stay public
+	auth, err := NewAuthFromConfig(cfg.Auth, cfg.DataDir)
 	if err != nil {
-		log.Fatalf("Failed to load inventory: %v", err)
+		fatal("invalid authentication configuration", err)
 	}
 
-	// Initialize the availability service with the inventory adapter
-	availabilityService := NewAvailabilityService(inventoryAdapter)
+	// Browsers on the allowed origins may call the API
+	cors := NewCORS(cfg.CORS)
 
-	// Initialize the HTTP handler with the availability service
-	handler := NewAvailabilityHandler(availabilityService)
+	// The server listens straight away so probes can answer while the
+	// inventory and rules load; the API answers 503 until they have
+	health := NewHealthHandler()
+	metrics := NewMetrics()
+	api := &StartupGate{}
 
 	// Register the endpoints
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			http.Redirect(w, r, "/docs", http.StatusMovedPermanently)
 			return
 		}
 		http.NotFound(w, r)
 	})
-	http.HandleFunc("/api/check-availability", handler.HandleCheckAvailability)
-	http.HandleFunc("/docs", HandleSwaggerUI)
-	http.HandleFunc("/openapi.json", HandleOpenAPI)
-
-	// Start the server
-	port := ":8080"
-	fmt.Printf("Starting server on http://localhost%s\n", port)
-	fmt.Println("Endpoints:")
-	fmt.Println("  - GET  / (redirects to /docs)")
-	fmt.Println("  - POST /api/check-availability")
-	fmt.Println("  - GET  /docs (Swagger UI Documentation)")
-	fmt.Println("  - GET  /openapi.json (OpenAPI Specification)")
-	fmt.Printf("Current day: %s (Weekend: %v)\n", time.Now().Weekday(), isWeekend())
-	fmt.Println("Inventory loaded from: inventory.json")
-	fmt.Println()
-
-	err = http.ListenAndServe(port, nil)
+	// Every API request counts against its client IP before authentication,
+	// so failed attempts are limited too; the routes add limits per client
+	limiter := NewRateLimiter(cfg.RateLimit)
+	metrics.SetRateLimiter(limiter)
+
+	// The API routes behind the gate are registered here too, so their
+	// route is known to the middleware before the gate opens
+	apiHandler := cors.Handler(limiter.LimitAddresses("/api/", cfg.RateLimit.Addresses, auth.Require(ScopeAvailabilityRead, api)))
+	mux.Handle("/api/", apiHandler)
+	mux.Handle("/api/check-availability", apiHandler)
+	mux.Handle("/api/warehouses", apiHandler)
+	mux.HandleFunc("/healthz", health.HandleLiveness)
+	mux.HandleFunc("/readyz", health.HandleReadiness)
+	mux.Handle("/metrics", auth.Require(ScopeAdmin, http.HandlerFunc(metrics.HandleMetrics)))
+	mux.HandleFunc("/docs", HandleSwaggerUI)
+	mux.HandleFunc("/openapi.json", HandleOpenAPI)
+
+	slog.Info("starting server",
+		"addr", cfg.ListenAddr,
+		"env", cfg.Env,
+		"endpoints", []string{
+			"GET / (redirects to /docs)",
+			"POST /api/check-availability",
+			"GET /api/warehouses",
+			"GET /healthz (liveness)",
+			"GET /readyz (readiness)",
+			"GET /metrics (Prometheus metrics)",
+			"GET /docs (Swagger UI Documentation)",
+			"GET /openapi.json (OpenAPI Specification)",
+		},
+		"tracing_endpoint", cfg.Tracing.Endpoint,
+		"auth", cfg.Auth.Enabled,
+		"weekday", time.Now().Weekday().String(),
+		"weekend", isWeekend())
+
+	// Listen before serving so a taken port fails startup right away
+	listener, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
-		log.Fatal("Server failed to start: ", err)
+		fatal("server failed to start", err)
 	}
+
+	// SIGTERM (container stop) and SIGINT (Ctrl+C) start a graceful
+	// shutdown; a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+
+	// Load the data in the background, retrying until it succeeds or the
+	// server shuts down. The loader hands back what to release once the
+	// server has drained
+	loaded := make(chan func(), 1)
+	go func() {
+		for {
+			release, err := loadData(cfg, api, limiter, health, metrics)
+			if err == nil {
+				loaded <- release
+				return
+			}
+			health.SetStartupError(err)
+			slog.Error("failed to load data, retrying", "retry_in", startupRetryInterval.String(), "error", err)
+			select {
+			case <-ctx.Done():
+				loaded <- func() {}
+				return
+			case <-time.After(startupRetryInterval):
+			}
+		}
+	}()
+
+	server := newServer(cfg.Server, cfg.ListenAddr, withRoute(mux, logRequests(metrics.Instrument(tracer.Middleware(mux)))))
+	err = serve(ctx, server, listener, time.Duration(cfg.Server.ShutdownTimeout))
+	stop()
+	release := <-loaded
+	release()
+
+	// Send the spans of the drained requests before exiting
+	flushCtx, cancel := context.WithTimeout(context.Background(), otlpSendTimeout)
+	if err := tracer.Shutdown(flushCtx); err != nil {
+		slog.Warn("failed to export spans", "error", err)
+	}
+	cancel()
+	if err != nil {
+		fatal("server stopped", err)
+	}
+	slog.Info("server stopped")
+}
+
+// fatal logs an error that ends the process and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// startupRetryInterval is how long to wait before loading the data again
+// after a failed startup
+const startupRetryInterval = 5 * time.Second
+
+// loadData loads the inventory and rules, opens the API gate with handlers
+// over them rate limited by limiter and hands their checks to the health
+// handler and their datasets to the metrics. Each tenant listed in the
+// tenants file gets its own data directory and is identified per request.
+// Without the file the service runs single-tenant on the files in the data
+// directory
+func loadData(cfg Config, api *StartupGate, limiter *RateLimiter, health *HealthHandler, metrics *Metrics) (release func(), err error) {
+	var checkAvailability, listWarehouses http.HandlerFunc
+	var tenants *TenantRegistry
+	var inventorySource string
+	var checks []func() HealthCheck
+	datasets := map[string]*Dataset{}
+	options := append(cfg.ServiceOptions(), WithMetrics(metrics))
+	tenantsFile := cfg.TenantsFile
+	if !filepath.IsAbs(tenantsFile) {
+		tenantsFile = filepath.Join(cfg.DataDir, tenantsFile)
+	}
+	if _, err := os.Stat(tenantsFile); err == nil {
+		tenants = NewTenantRegistry(tenantsFile, cfg.Inventory, options...)
+		err := tenants.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tenants: %w", err)
+		}
+		release = tenants.Close
+
+		checkAvailability = tenants.Handler((*AvailabilityHandler).HandleCheckAvailability)
+		listWarehouses = tenants.Handler((*AvailabilityHandler).HandleListWarehouses)
+		checks = tenants.Checks()
+		var sources []string
+		for _, t := range tenants.List() {
+			sources = append(sources, fmt.Sprintf("%s=%s", t.ID, t.dataset.InventorySource))
+			datasets[t.ID] = t.dataset
+		}
+		inventorySource = strings.Join(sources, ", ")
+	} else {
+		dataset, err := LoadDataset(cfg.DataDir, cfg.Inventory, options...)
+		if err != nil {
+			return nil, err
+		}
+		release = dataset.Close
+
+		// Initialize the HTTP handler with the availability service
+		handler := NewAvailabilityHandler(dataset.Service, dataset.Warehouses)
+		checkAvailability = handler.HandleCheckAvailability
+		listWarehouses = handler.HandleListWarehouses
+		checks = append(checks, dataset.Monitor.Check)
+		datasets[""] = dataset
+		inventorySource = dataset.InventorySource
+	}
+
+	// Rate limits and deadlines are set per route
+	timeout := time.Duration(cfg.Server.RequestTimeout)
+	limiter.SetTenants(tenants)
+	routes := http.NewServeMux()
+	routes.Handle("/api/check-availability", limiter.Limit("/api/check-availability", cfg.RateLimit.CheckAvailability, withRequestTimeout(timeout, checkAvailability)))
+	routes.Handle("/api/warehouses", limiter.Limit("/api/warehouses", cfg.RateLimit.Warehouses, withRequestTimeout(timeout, listWarehouses)))
+	api.Open(routes)
+	health.SetChecks(checks...)
+	metrics.SetDatasets(datasets)
+	slog.Info("inventory loaded", "source", inventorySource)
+	return release, nil
 }
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultLatencyBuckets are the histogram bucket bounds, in seconds
+var defaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects the service's metrics and serves them in the Prometheus
+// text exposition format. Request and outcome metrics are recorded as they
+// happen; inventory and rate limit metrics are read from the datasets and
+// the rate limiter when scraped
+type Metrics struct {
+	requests       *counterVec
+	requestLatency *histogramVec
+	checks         *counterVec
+
+	mu          sync.Mutex
+	datasets    map[string]*Dataset // tenant ID ("" in single-tenant mode) -> dataset
+	rateLimiter *RateLimiter
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: newCounterVec("availability_http_requests_total",
+			"HTTP requests handled, by route and status code.", "route", "status"),
+		requestLatency: newHistogramVec("availability_http_request_duration_seconds",
+			"HTTP request latency, by route and status code.", defaultLatencyBuckets, "route", "status"),
+		checks: newCounterVec("availability_checks_total",
+			"Availability checks answered, by tenant, warehouse and reason code.", "tenant", "warehouse", "reason_code"),
+		datasets: map[string]*Dataset{},
+	}
+}
+
+// SetDatasets sets the datasets whose inventory metrics are reported, keyed by tenant ID
+func (m *Metrics) SetDatasets(datasets map[string]*Dataset) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.datasets = datasets
+}
+
+// SetRateLimiter sets the rate limiter whose limits are reported
+func (m *Metrics) SetRateLimiter(limiter *RateLimiter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rateLimiter = limiter
+}
+
+// ObserveCheck counts one availability check outcome
+func (m *Metrics) ObserveCheck(tenant, warehouse, reasonCode string) {
+	m.checks.inc(tenant, warehouse, reasonCode)
+}
+
+// Instrument records the count and latency of every request served by next.
+// Requests are labelled with the route resolved by withRoute, so arbitrary
+// paths do not create new series
+func (m *Metrics) Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := routeFromContext(r.Context())
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(recorder.status)
+		m.requests.inc(route, status)
+		m.requestLatency.observe(time.Since(start).Seconds(), route, status)
+	})
+}
+
+// HandleMetrics handles GET /metrics
+func (m *Metrics) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed. Use GET", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	err := m.Write(w)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "writing metrics", "error", err)
+	}
+}
+
+// Write writes every metric in the Prometheus text exposition format
+func (m *Metrics) Write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	m.requests.write(out)
+	m.requestLatency.write(out)
+	m.checks.write(out)
+	m.writeInventory(out)
+	m.writeRateLimits(out)
+	return out.Flush()
+}
+
+// writeRateLimits writes the configured limit and activity of every rate limited route
+func (m *Metrics) writeRateLimits(out *bufio.Writer) {
+	m.mu.Lock()
+	limiter := m.rateLimiter
+	m.mu.Unlock()
+	stats := limiter.Stats()
+	if len(stats) == 0 {
+		return
+	}
+
+	rate := newGaugeVec("availability_rate_limit_requests_per_second",
+		"Requests per second each client may make on average, by route.", "route")
+	burst := newGaugeVec("availability_rate_limit_burst",
+		"Requests each client may make at once, by route.", "route")
+	clients := newGaugeVec("availability_rate_limit_clients",
+		"Clients currently tracked by the rate limiter, by route.", "route")
+	limited := newCounterVec("availability_rate_limited_requests_total",
+		"Requests rejected with 429 for exceeding the rate limit, by route.", "route")
+	for _, s := range stats {
+		rate.set(s.Limit.Rate, s.Route)
+		burst.set(float64(s.Limit.Burst), s.Route)
+		clients.set(float64(s.Clients), s.Route)
+		limited.add(float64(s.Limited), s.Route)
+	}
+	rate.write(out)
+	burst.write(out)
+	clients.write(out)
+	limited.write(out)
+}
+
+// writeInventory writes the inventory metrics of every dataset
+func (m *Metrics) writeInventory(out *bufio.Writer) {
+	m.mu.Lock()
+	tenants := make([]string, 0, len(m.datasets))
+	for tenant := range m.datasets {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	stats := make([]InventoryStats, len(tenants))
+	caches := make([]*CacheStats, len(tenants))
+	for i, tenant := range tenants {
+		d := m.datasets[tenant]
+		stats[i] = d.Monitor.Stats()
+		if d.Cache != nil {
+			cache := d.Cache.Stats()
+			caches[i] = &cache
+		}
+	}
+	m.mu.Unlock()
+
+	lookups := newHistogramVec("availability_inventory_lookup_duration_seconds",
+		"Latency of lookups that reached the inventory source, by tenant.", defaultLatencyBuckets, "tenant")
+	errs := newCounterVec("availability_inventory_lookup_errors_total",
+		"Inventory lookups the source failed to answer, by tenant and kind (unavailable, timeout, other).", "tenant", "kind")
+	items := newGaugeVec("availability_inventory_items",
+		"Product and warehouse rows currently loaded, by tenant. Absent for sources that are not held in memory.", "tenant")
+	reloaded := newGaugeVec("availability_inventory_last_reload_timestamp_seconds",
+		"Unix time of the last successful inventory load or reload, by tenant.", "tenant")
+	cacheHits := newCounterVec("availability_inventory_cache_hits_total",
+		"Lookups answered from the inventory cache, by tenant.", "tenant")
+	cacheMisses := newCounterVec("availability_inventory_cache_misses_total",
+		"Lookups the inventory cache passed to the source, by tenant.", "tenant")
+	cacheEntries := newGaugeVec("availability_inventory_cache_entries",
+		"Entries currently in the inventory cache, by tenant.", "tenant")
+
+	for i, tenant := range tenants {
+		s := stats[i]
+		lookups.set(s.Lookups, tenant)
+		for kind, count := range s.Errors {
+			errs.add(float64(count), tenant, kind)
+		}
+		if s.Items >= 0 {
+			items.set(float64(s.Items), tenant)
+		}
+		if !s.LastReload.IsZero() {
+			reloaded.set(float64(s.LastReload.UnixMilli())/1000, tenant)
+		}
+		if cache := caches[i]; cache != nil {
+			cacheHits.add(float64(cache.Hits+cache.NegativeHits), tenant)
+			cacheMisses.add(float64(cache.Misses), tenant)
+			cacheEntries.set(float64(cache.Size), tenant)
+		}
+	}
+
+	lookups.write(out)
+	errs.write(out)
+	items.write(out)
+	reloaded.write(out)
+	cacheHits.write(out)
+	cacheMisses.write(out)
+	cacheEntries.write(out)
+}
+
+// statusRecorder remembers the status code and body size written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// metricVec holds the series of one metric family, keyed by label values
+type metricVec struct {
+	name   string
+	help   string
+	kind   string // counter, gauge or histogram
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is one labelled time series
+type series struct {
+	labelValues []string
+	value       float64
+	histogram   *Histogram
+}
+
+func newMetricVec(name, help, kind string, labels []string) *metricVec {
+	return &metricVec{name: name, help: help, kind: kind, labels: labels, series: map[string]*series{}}
+}
+
+// get returns the series for the label values, creating it if needed. v.mu must be held
+func (v *metricVec) get(labelValues []string) *series {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// write writes the family in the text exposition format, series sorted by labels
+func (v *metricVec) write(out *bufio.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(out, "# HELP %s %s\n", v.name, v.help)
+	fmt.Fprintf(out, "# TYPE %s %s\n", v.name, v.kind)
+	for _, key := range keys {
+		s := v.series[key]
+		if s.histogram == nil {
+			fmt.Fprintf(out, "%s%s %s\n", v.name, formatLabels(v.labels, s.labelValues, ""), formatValue(s.value))
+			continue
+		}
+		h := s.histogram
+		var cumulative uint64
+		for i, bound := range h.Bounds {
+			cumulative += h.Counts[i]
+			fmt.Fprintf(out, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(out, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.labelValues, "+Inf"), h.Count)
+		fmt.Fprintf(out, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.labelValues, ""), formatValue(h.Sum))
+		fmt.Fprintf(out, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.labelValues, ""), h.Count)
+	}
+}
+
+// counterVec is a family of counters
+type counterVec struct{ *metricVec }
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{newMetricVec(name, help, "counter", labels)}
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.add(1, labelValues...)
+}
+
+func (c *counterVec) add(delta float64, labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues).value += delta
+}
+
+// gaugeVec is a family of gauges
+type gaugeVec struct{ *metricVec }
+
+func newGaugeVec(name, help string, labels ...string) *gaugeVec {
+	return &gaugeVec{newMetricVec(name, help, "gauge", labels)}
+}
+
+func (g *gaugeVec) set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.get(labelValues).value = value
+}
+
+// histogramVec is a family of histograms sharing bucket bounds
+type histogramVec struct {
+	*metricVec
+	bounds []float64
+}
+
+func newHistogramVec(name, help string, bounds []float64, labels ...string) *histogramVec {
+	return &histogramVec{newMetricVec(name, help, "histogram", labels), bounds}
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues)
+	if s.histogram == nil {
+		s.histogram = NewHistogram(h.bounds)
+	}
+	s.histogram.Observe(value)
+}
+
+// set replaces a series with a snapshot taken elsewhere
+func (h *histogramVec) set(snapshot Histogram, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.get(labelValues).histogram = &snapshot
+}
+
+// Histogram counts observations into buckets. It is not safe for
+// concurrent use; its owner guards it
+type Histogram struct {
+	Bounds []float64 // upper bounds of the buckets, ascending
+	Counts []uint64  // observations per bucket, not cumulative
+	Sum    float64
+	Count  uint64
+}
+
+// NewHistogram creates a histogram with the given bucket bounds
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]uint64, len(bounds))}
+}
+
+// Observe records one value; values above the last bound only count towards +Inf
+func (h *Histogram) Observe(value float64) {
+	h.Sum += value
+	h.Count++
+	if i := sort.SearchFloat64s(h.Bounds, value); i < len(h.Bounds) {
+		h.Counts[i]++
+	}
+}
+
+// Snapshot returns a copy of the histogram
+func (h *Histogram) Snapshot() Histogram {
+	snapshot := *h
+	snapshot.Counts = append([]uint64(nil), h.Counts...)
+	return snapshot
+}
+
+// labelEscaper escapes label values for the text exposition format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders {name="value",...}, adding le when it is set
+func formatLabels(names, values []string, le string) string {
+	var pairs []string
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatValue renders a sample value the way Prometheus expects
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrapeMetrics serves /metrics and returns the exposition text
+func scrapeMetrics(t *testing.T, metrics *Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	metrics.HandleMetrics(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	return rr.Body.String()
+}
+
+// assertMetric fails unless the exposition contains the exact sample line
+func assertMetric(t *testing.T, text, line string) {
+	t.Helper()
+	for _, got := range strings.Split(text, "\n") {
+		if got == line {
+			return
+		}
+	}
+	t.Errorf("Expected metric line %q in:\n%s", line, text)
+}
+
+func TestMetrics_InstrumentLabelsByRouteAndStatus(t *testing.T) {
+	metrics := NewMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/warehouses", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/api/check-availability", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "product_id is required", http.StatusBadRequest)
+	})
+	handler := withRoute(mux, metrics.Instrument(mux))
+
+	for _, path := range []string{"/api/warehouses", "/api/warehouses", "/api/check-availability", "/unknown/1", "/unknown/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_http_requests_total{route="/api/warehouses",status="200"} 2`)
+	assertMetric(t, text, `availability_http_requests_total{route="/api/check-availability",status="400"} 1`)
+	assertMetric(t, text, `availability_http_requests_total{route="unmatched",status="404"} 2`)
+	assertMetric(t, text, `availability_http_request_duration_seconds_count{route="/api/warehouses",status="200"} 2`)
+	assertMetric(t, text, `availability_http_request_duration_seconds_bucket{route="/api/warehouses",status="200",le="+Inf"} 2`)
+	assertMetric(t, text, "# TYPE availability_http_request_duration_seconds histogram")
+}
+
+func TestMetrics_InstrumentLabelsRoutesBehindAuth(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]APIKey{{Name: "storefront", SHA256: hashAPIKey("storefront-secret"), Scopes: []string{ScopeAvailabilityRead}}})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	metrics := NewMetrics()
+	// The API routes sit on an inner mux behind authentication, as in main
+	routes := http.NewServeMux()
+	routes.HandleFunc("/api/warehouses", func(w http.ResponseWriter, r *http.Request) {})
+	mux := http.NewServeMux()
+	authed := NewAuth(keys).Require(ScopeAvailabilityRead, routes)
+	mux.Handle("/api/", authed)
+	mux.Handle("/api/warehouses", authed)
+	handler := withRoute(mux, metrics.Instrument(mux))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+	req.Header.Set("Authorization", "Bearer storefront-secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_http_requests_total{route="/api/warehouses",status="200"} 1`)
+}
+
+// newMetricsService returns a service over the mock adapter and the shipped
+// warehouses that reports to metrics
+func newMetricsService(t *testing.T, metrics *Metrics) *AvailabilityService {
+	t.Helper()
+	warehouses := NewWarehouseRegistry("warehouses.json")
+	if err := warehouses.Load(); err != nil {
+		t.Fatalf("Failed to load warehouses: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithWarehouseRegistry(warehouses), WithMetrics(metrics))
+}
+
+func TestMetrics_CountsCheckOutcomes(t *testing.T) {
+	metrics := NewMetrics()
+	service := newMetricsService(t, metrics)
+
+	service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+	service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 2, WarehouseLocation: "DE-Berlin"})
+	service.CheckAvailability(t.Context(), Request{ProductID: "PROD-999", Quantity: 1, WarehouseLocation: "DE-Berlin"})
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_checks_total{tenant="",warehouse="DE-Berlin",reason_code="sufficient_stock"} 2`)
+	assertMetric(t, text, `availability_checks_total{tenant="",warehouse="DE-Berlin",reason_code="product_not_found"} 1`)
+}
+
+func TestMetrics_UnknownWarehousesShareOneLabel(t *testing.T) {
+	metrics := NewMetrics()
+	service := newMetricsService(t, metrics)
+
+	for i := range 3 {
+		service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: fmt.Sprintf("XX-Random-%d", i)})
+	}
+
+	text := scrapeMetrics(t, metrics)
+	if strings.Contains(text, "XX-Random") {
+		t.Errorf("Expected client-supplied warehouses kept out of the labels, got:\n%s", text)
+	}
+	assertMetric(t, text, `availability_checks_total{tenant="",warehouse="unknown",reason_code="product_not_found"} 3`)
+}
+
+func TestMetrics_InventoryFromDatasets(t *testing.T) {
+	root := t.TempDir()
+	writeTenantDir(t, root, "retail", `[
+		{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 100},
+		{"product_id": "PROD-456", "warehouse": "DE-Berlin", "stock_level": 40}
+	]`)
+	inventory := DefaultConfig().Inventory
+	inventory.CacheTTL = Duration(time.Minute)
+	dataset, err := LoadDataset(root+"/retail", inventory)
+	if err != nil {
+		t.Fatalf("Failed to load dataset: %v", err)
+	}
+	t.Cleanup(dataset.Close)
+
+	metrics := NewMetrics()
+	metrics.SetDatasets(map[string]*Dataset{"retail": dataset})
+	dataset.Inventory.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	dataset.Inventory.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin") // from cache
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_inventory_items{tenant="retail"} 2`)
+	assertMetric(t, text, `availability_inventory_lookup_duration_seconds_count{tenant="retail"} 1`)
+	assertMetric(t, text, `availability_inventory_cache_hits_total{tenant="retail"} 1`)
+	assertMetric(t, text, `availability_inventory_cache_misses_total{tenant="retail"} 1`)
+	assertMetric(t, text, fmt.Sprintf(`availability_inventory_last_reload_timestamp_seconds{tenant="retail"} %s`, formatValue(float64(now().UnixMilli())/1000)))
+}
+
+func TestMetrics_LookupErrorsByKind(t *testing.T) {
+	monitor := NewMonitoredInventoryAdapter("inventory", &ErrorInventoryAdapter{err: fmt.Errorf("%w: deadline exceeded", ErrTimeout)}, 0)
+	monitor.LoadInventory()
+	monitor.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+	monitor.GetStockLevel(t.Context(), "PROD-123", "DE-Berlin")
+
+	metrics := NewMetrics()
+	metrics.SetDatasets(map[string]*Dataset{"": {Monitor: monitor}})
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_inventory_lookup_errors_total{tenant="",kind="timeout"} 2`)
+	if strings.Contains(text, "availability_inventory_items") {
+		t.Error("Expected no item count for a source that does not report its size")
+	}
+}
+
+func TestMetrics_RateLimits(t *testing.T) {
+	limiter, _ := newTestLimiter(false)
+	handler := limiter.Limit("/api/warehouses", RouteLimit{Rate: 5, Burst: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	limitedRequest(handler, "192.0.2.1:1234", nil)
+	limitedRequest(handler, "192.0.2.1:1234", nil)
+
+	metrics := NewMetrics()
+	metrics.SetRateLimiter(limiter)
+
+	text := scrapeMetrics(t, metrics)
+	assertMetric(t, text, `availability_rate_limit_requests_per_second{route="/api/warehouses"} 5`)
+	assertMetric(t, text, `availability_rate_limit_burst{route="/api/warehouses"} 1`)
+	assertMetric(t, text, `availability_rate_limit_clients{route="/api/warehouses"} 1`)
+	assertMetric(t, text, `availability_rate_limited_requests_total{route="/api/warehouses"} 1`)
+}
+
+func TestHistogram_BucketsAreCumulative(t *testing.T) {
+	vec := newHistogramVec("test_seconds", "Test.", []float64{0.1, 1})
+	for _, value := range []float64{0.05, 0.1, 0.5, 3} {
+		vec.observe(value)
+	}
+
+	var out strings.Builder
+	metrics := &Metrics{requests: newCounterVec("unused", ""), requestLatency: vec, checks: newCounterVec("unused", "")}
+	if err := metrics.Write(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	want := `# HELP test_seconds Test.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.1"} 2
+test_seconds_bucket{le="1"} 3
+test_seconds_bucket{le="+Inf"} 4
+test_seconds_sum 3.65
+test_seconds_count 4
+`
+	if out.String() != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestFormatLabels_EscapesValues(t *testing.T) {
+	got := formatLabels([]string{"warehouse"}, []string{"DE-\"Berlin\"\\\n"}, "")
+	want := `{warehouse="DE-\"Berlin\"\\\n"}`
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
@@ -1,18 +1,97 @@
 package main
 
+import "time"
+
 // Request represents the incoming availability check request
 type Request struct {
 	ProductID         string `json:"product_id"`
 	Quantity          int    `json:"quantity"`
 	WarehouseLocation string `json:"warehouse_location"`
+	ShipDate          string `json:"ship_date,omitempty"` // desired ship date, "2006-01-02"; enables available-to-promise
+	Unit              string `json:"unit,omitempty"`      // unit of Quantity; defaults to the product's base unit
+	Channel           string `json:"channel,omitempty"`   // sales channel: web, marketplace or b2b
+	TenantID          string `json:"-"`                   // set from the resolved tenant, never by the client
 }
 
 // Response represents the availability check response
 type Response struct {
-	Available         bool   `json:"available"`
+	Available         bool        `json:"available"`
+	AvailableQuantity int         `json:"available_quantity"`
+	Status            string      `json:"status"` // available, backorderable or unavailable
+	ReasonCode        string      `json:"reason_code"`
+	Reason            string      `json:"reason"`
+	Warehouse         string      `json:"warehouse"`
+	Source            *SourceInfo `json:"source,omitempty"`
+
+	// The requested quantity and its conversion to the product's base unit,
+	// which every other quantity in the response is expressed in
+	Unit         string `json:"unit,omitempty"`
+	Quantity     int    `json:"quantity,omitempty"`
+	BaseUnit     string `json:"base_unit,omitempty"`
+	BaseQuantity int    `json:"base_quantity,omitempty"`
+
+	// How the requested quantity splits between stock and backorder
+	InStockQuantity     int `json:"in_stock_quantity"`
+	BackorderedQuantity int `json:"backordered_quantity"`
+
+	// Bundle reports component availability when a bundle was requested
+	Bundle *BundleAvailability `json:"bundle,omitempty"`
+
+	// Substitutes lists alternatives in stock when the product is unavailable
+	Substitutes []Substitute `json:"substitutes,omitempty"`
+
+	// EarliestAvailableDate is when an unavailable request could ship, or
+	// null if no replenishment is scheduled
+	EarliestAvailableDate *string `json:"earliest_available_date"`
+
+	// ChannelAllocation reports the stock the request's channel could draw on
+	// when allocation rules apply at the warehouse
+	ChannelAllocation *ChannelAllocation `json:"channel_allocation,omitempty"`
+
+	// ShortDatedQuantity is stock left out because its lots expire too soon
+	ShortDatedQuantity int `json:"short_dated_quantity,omitempty"`
+
+	// Available-to-promise fields, set when the request has a ship date
+	ShipDate            string `json:"ship_date,omitempty"`
+	InboundQuantity     int    `json:"inbound_quantity,omitempty"`
+	EarliestPromiseDate string `json:"earliest_promise_date,omitempty"`
+}
+
+// BundleAvailability reports how many bundles can be built and which component limits it
+type BundleAvailability struct {
+	BundleID          string                  `json:"bundle_id"`
+	BuildableQuantity int                     `json:"buildable_quantity"`
+	LimitingComponent string                  `json:"limiting_component"`
+	Components        []ComponentAvailability `json:"components"`
+}
+
+// ComponentAvailability is one component's contribution to a bundle check
+type ComponentAvailability struct {
+	ProductID         string `json:"product_id"`
+	QuantityPerBundle int    `json:"quantity_per_bundle"`
+	AvailableQuantity int    `json:"available_quantity"` // after the component's reserve buffer
+	BuildableQuantity int    `json:"buildable_quantity"` // bundles this component alone allows
+}
+
+// ChannelAllocation splits the stock a channel can draw on into its own
+// allocation and the shared pool no channel has claimed
+type ChannelAllocation struct {
+	Channel           string `json:"channel"`
+	AllocatedQuantity int    `json:"allocated_quantity"`
+	SharedQuantity    int    `json:"shared_quantity"`
+}
+
+// Substitute is an alternative product available in the requested quantity
+type Substitute struct {
+	ProductID         string `json:"product_id"`
 	AvailableQuantity int    `json:"available_quantity"`
-	Reason            string `json:"reason"`
-	Warehouse         string `json:"warehouse"`
+}
+
+// SourceInfo reports which inventory source answered and how stale its data is
+type SourceInfo struct {
+	Name       string    `json:"name"`
+	AsOf       time.Time `json:"as_of"`
+	AgeSeconds int64     `json:"age_seconds"`
 }
 
 // InventoryItem represents stock information for a product at a warehouse
@@ -20,4 +99,12 @@ type InventoryItem struct {
 	ProductID  string `json:"product_id"`
 	Warehouse  string `json:"warehouse"`
 	StockLevel int    `json:"stock_level"`
+	Lots       []Lot  `json:"lots,omitempty"` // perishable stock only; quantities add up to StockLevel
+}
+
+// Lot is a batch of stock sharing an expiry date
+type Lot struct {
+	LotNumber  string `json:"lot_number"`
+	Quantity   int    `json:"quantity"`
+	ExpiryDate string `json:"expiry_date"` // "2006-01-02"
 }
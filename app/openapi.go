@@ -29,6 +29,12 @@ var openAPISpec = map[string]interface{}{
 				"description": "Check if a product is available at a specific warehouse location. Applies 10% reserve buffer and weekend 2x quantity rules.",
 				"operationId": "checkAvailability",
 				"tags":        []string{"Availability"},
+				"security":    []map[string][]string{{"bearerAuth": {}}},
+				"parameters": []map[string]interface{}{
+					{"$ref": "#/components/parameters/APIKey"},
+					{"$ref": "#/components/parameters/TenantID"},
+					{"$ref": "#/components/parameters/RequestID"},
+				},
 				"requestBody": map[string]interface{}{
 					"required": true,
 					"content": map[string]interface{}{
@@ -71,6 +77,7 @@ var openAPISpec = map[string]interface{}{
 										"value": map[string]interface{}{
 											"available":          true,
 											"available_quantity": 90,
+											"reason_code":        "sufficient_stock",
 											"reason":             "Sufficient stock available",
 											"warehouse":          "DE-Berlin",
 										},
@@ -80,6 +87,7 @@ var openAPISpec = map[string]interface{}{
 										"value": map[string]interface{}{
 											"available":          false,
 											"available_quantity": 0,
+											"reason_code":        "out_of_stock",
 											"reason":             "Product is out of stock",
 											"warehouse":          "DE-Berlin",
 										},
@@ -87,19 +95,12 @@ var openAPISpec = map[string]interface{}{
 									"insufficient": map[string]interface{}{
 										"summary": "Insufficient stock",
 										"value": map[string]interface{}{
-											"available":          false,
-											"available_quantity": 4,
-											"reason":             "Insufficient stock (requires 5 units, only 4 available after reserve)",
-											"warehouse":          "US-NewYork",
-										},
-									},
-									"notFound": map[string]interface{}{
-										"summary": "Product not in warehouse",
-										"value": map[string]interface{}{
-											"available":          false,
-											"available_quantity": 0,
-											"reason":             "Product not found in specified warehouse",
-											"warehouse":          "UK-London",
+											"available":               false,
+											"available_quantity":      4,
+											"reason_code":             "insufficient_stock",
+											"reason":                  "Insufficient stock (requires 5 units, only 4 available after reserve)",
+											"warehouse":               "US-NewYork",
+											"earliest_available_date": "2026-10-26",
 										},
 									},
 								},
@@ -107,8 +108,13 @@ var openAPISpec = map[string]interface{}{
 						},
 					},
 					"400": map[string]interface{}{
-						"description": "Bad request - invalid input",
+						"description": "Bad request - invalid input, or a unit the product cannot be requested in (reason_code unknown_unit)",
 						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"$ref": "#/components/schemas/AvailabilityResponse",
+								},
+							},
 							"text/plain": map[string]interface{}{
 								"schema": map[string]interface{}{
 									"type": "string",
@@ -118,18 +124,75 @@ var openAPISpec = map[string]interface{}{
 										"summary": "Missing product_id",
 										"value":   "product_id is required",
 									},
+									"invalidShipDate": map[string]interface{}{
+										"summary": "Invalid ship_date",
+										"value":   "ship_date must be a date in YYYY-MM-DD format",
+									},
 									"invalidQuantity": map[string]interface{}{
 										"summary": "Invalid quantity",
 										"value":   "quantity must be greater than 0",
 									},
+									"unknownChannel": map[string]interface{}{
+										"summary": "Unknown channel",
+										"value":   "channel must be one of web, marketplace, b2b",
+									},
 									"missingWarehouse": map[string]interface{}{
 										"summary": "Missing warehouse_location",
 										"value":   "warehouse_location is required",
 									},
+									"unknownWarehouse": map[string]interface{}{
+										"summary": "Unknown warehouse_location",
+										"value":   "warehouse_location \"DE-berlin\" is not a known warehouse; did you mean \"DE-Berlin\"?",
+									},
 								},
 							},
 						},
 					},
+					"404": map[string]interface{}{
+						"description": "Product not in the catalog, or product or warehouse not found in the inventory source",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"$ref": "#/components/schemas/AvailabilityResponse",
+								},
+								"examples": map[string]interface{}{
+									"notFound": map[string]interface{}{
+										"summary": "Product not in warehouse",
+										"value": map[string]interface{}{
+											"available":          false,
+											"available_quantity": 0,
+											"reason_code":        "product_not_found",
+											"reason":             "Product not found in specified warehouse",
+											"warehouse":          "UK-London",
+										},
+									},
+								},
+							},
+						},
+					},
+					"503": map[string]interface{}{
+						"description": "Inventory source unavailable (reason_code source_unavailable)",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"$ref": "#/components/schemas/AvailabilityResponse",
+								},
+							},
+						},
+					},
+					"504": map[string]interface{}{
+						"description": "Inventory source timed out (reason_code source_timeout)",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"$ref": "#/components/schemas/AvailabilityResponse",
+								},
+							},
+						},
+					},
+					"401": map[string]interface{}{"$ref": "#/components/responses/Unauthorized"},
+					"403": map[string]interface{}{"$ref": "#/components/responses/Forbidden"},
+					"429": map[string]interface{}{"$ref": "#/components/responses/TooManyRequests"},
 					"405": map[string]interface{}{
 						"description": "Method not allowed",
 						"content": map[string]interface{}{
@@ -144,16 +207,249 @@ var openAPISpec = map[string]interface{}{
 				},
 			},
 		},
+		"/api/warehouses": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "List warehouses",
+				"description": "List every registered warehouse with its metadata.",
+				"operationId": "listWarehouses",
+				"tags":        []string{"Warehouses"},
+				"security":    []map[string][]string{{"bearerAuth": {}}},
+				"parameters": []map[string]interface{}{
+					{"$ref": "#/components/parameters/APIKey"},
+					{"$ref": "#/components/parameters/TenantID"},
+					{"$ref": "#/components/parameters/RequestID"},
+				},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Registered warehouses",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"warehouses": map[string]interface{}{
+											"type": "array",
+											"items": map[string]interface{}{
+												"$ref": "#/components/schemas/Warehouse",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"401": map[string]interface{}{"$ref": "#/components/responses/Unauthorized"},
+					"403": map[string]interface{}{"$ref": "#/components/responses/Forbidden"},
+					"429": map[string]interface{}{"$ref": "#/components/responses/TooManyRequests"},
+				},
+			},
+		},
+		"/healthz": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Liveness probe",
+				"description": "Reports that the process is up and serving. Never checks dependencies.",
+				"operationId": "liveness",
+				"tags":        []string{"Health"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "The process is alive",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"example": map[string]interface{}{"status": "ok"},
+							},
+						},
+					},
+				},
+			},
+		},
+		"/readyz": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Readiness probe",
+				"description": "Reports whether the service can answer availability checks. Not ready until the inventory and rules have loaded or while a dependency check fails; degraded while the inventory source is failing lookups, failed its last reload or serves data older than inventory.max_age.",
+				"operationId": "readiness",
+				"tags":        []string{"Health"},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Ready or degraded",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/Readiness"},
+								"example": map[string]interface{}{
+									"status": "degraded",
+									"checks": []map[string]interface{}{
+										{"name": "inventory", "status": "degraded", "message": "last 3 lookups failed: inventory source unavailable: connection refused", "as_of": "2024-01-10T11:58:00Z"},
+									},
+								},
+							},
+						},
+					},
+					"503": map[string]interface{}{
+						"description": "Not ready: still starting or a dependency check is failing",
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{"$ref": "#/components/schemas/Readiness"},
+								"example": map[string]interface{}{
+									"status": "not_ready",
+									"checks": []map[string]interface{}{
+										{"name": "startup", "status": "failing", "message": "loading inventory and rules"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		"/metrics": map[string]interface{}{
+			"get": map[string]interface{}{
+				"summary":     "Prometheus metrics",
+				"description": "Request counts and latency by route and status, availability outcomes by tenant, warehouse and reason code, and per-tenant inventory lookup latency, lookup errors, loaded rows, last reload time and cache activity.",
+				"operationId": "metrics",
+				"tags":        []string{"Health"},
+				"security":    []map[string][]string{{"bearerAuth": {}}},
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{
+						"description": "Metrics in the Prometheus text exposition format",
+						"content": map[string]interface{}{
+							"text/plain": map[string]interface{}{
+								"example": "availability_checks_total{tenant=\"\",warehouse=\"DE-Berlin\",reason_code=\"sufficient_stock\"} 42\n",
+							},
+						},
+					},
+					"401": map[string]interface{}{"$ref": "#/components/responses/Unauthorized"},
+					"403": map[string]interface{}{"$ref": "#/components/responses/Forbidden"},
+				},
+			},
+		},
 	},
 	"components": map[string]interface{}{
+		"securitySchemes": map[string]interface{}{
+			"bearerAuth": map[string]interface{}{
+				"type":        "http",
+				"scheme":      "bearer",
+				"description": "Required when auth.enabled is set: a static API key or an RS256 JWT. The /api/ endpoints need the availability:read scope and /metrics the admin scope; admin grants every scope",
+			},
+		},
+		"responses": map[string]interface{}{
+			"Unauthorized": map[string]interface{}{
+				"description": "Authentication is enabled and the bearer credential is missing or invalid",
+				"content": map[string]interface{}{
+					"text/plain": map[string]interface{}{"example": "authentication required"},
+				},
+			},
+			"Forbidden": map[string]interface{}{
+				"description": "The credential lacks the endpoint's scope, or is limited to another tenant",
+				"content": map[string]interface{}{
+					"text/plain": map[string]interface{}{"example": "credentials do not grant availability:read"},
+				},
+			},
+			"TooManyRequests": map[string]interface{}{
+				"description": "Rate limiting is enabled and the client has spent its burst; limited responses carry the RateLimit-* headers too",
+				"headers": map[string]interface{}{
+					"Retry-After": map[string]interface{}{
+						"description": "Seconds until the client may retry",
+						"schema":      map[string]interface{}{"type": "integer"},
+					},
+					"RateLimit-Limit": map[string]interface{}{
+						"description": "Requests the client may make at once on this route",
+						"schema":      map[string]interface{}{"type": "integer"},
+					},
+					"RateLimit-Remaining": map[string]interface{}{
+						"description": "Requests left before the client is limited",
+						"schema":      map[string]interface{}{"type": "integer"},
+					},
+					"RateLimit-Reset": map[string]interface{}{
+						"description": "Seconds until the full burst is available again",
+						"schema":      map[string]interface{}{"type": "integer"},
+					},
+				},
+				"content": map[string]interface{}{
+					"text/plain": map[string]interface{}{"example": "Too many requests, retry in 2s"},
+				},
+			},
+		},
+		"parameters": map[string]interface{}{
+			"RequestID": map[string]interface{}{
+				"name":        "X-Request-ID",
+				"in":          "header",
+				"required":    false,
+				"description": "Correlation ID for the request's log lines, up to 128 characters of letters, digits and - _ . :; generated when missing or malformed, and always echoed in the X-Request-ID response header",
+				"schema":      map[string]interface{}{"type": "string", "maxLength": 128},
+			},
+			"APIKey": map[string]interface{}{
+				"name":        "X-API-Key",
+				"in":          "header",
+				"required":    false,
+				"description": "Multi-tenant deployments: API key identifying the tenant. An invalid key is rejected with 401",
+				"schema":      map[string]interface{}{"type": "string"},
+			},
+			"TenantID": map[string]interface{}{
+				"name":        "X-Tenant-ID",
+				"in":          "header",
+				"required":    false,
+				"description": "Multi-tenant deployments: tenant ID. Must match the API key's tenant (403 otherwise); on its own it is only accepted for tenants without API keys",
+				"schema":      map[string]interface{}{"type": "string"},
+			},
+		},
 		"schemas": map[string]interface{}{
+			"Readiness": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"status": map[string]interface{}{
+						"type": "string",
+						"enum": []string{"ready", "degraded", "not_ready"},
+					},
+					"checks": map[string]interface{}{
+						"type": "array",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"name":    map[string]interface{}{"type": "string", "description": "Dependency checked, e.g. inventory or inventory:<tenant ID>"},
+								"status":  map[string]interface{}{"type": "string", "enum": []string{"ok", "degraded", "failing"}},
+								"message": map[string]interface{}{"type": "string"},
+								"as_of":   map[string]interface{}{"type": "string", "format": "date-time", "description": "When the data last served by the dependency was produced"},
+							},
+						},
+					},
+				},
+			},
+			"Warehouse": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"code": map[string]interface{}{
+						"type":    "string",
+						"example": "DE-Berlin",
+					},
+					"name": map[string]interface{}{
+						"type":    "string",
+						"example": "Berlin Fulfilment Centre",
+					},
+					"country": map[string]interface{}{
+						"type":    "string",
+						"example": "DE",
+					},
+					"time_zone": map[string]interface{}{
+						"type":    "string",
+						"example": "Europe/Berlin",
+					},
+					"active": map[string]interface{}{
+						"type":    "boolean",
+						"example": true,
+					},
+					"shipping_cutoff": map[string]interface{}{
+						"type":        "string",
+						"description": "Local time of day after which orders ship the next day",
+						"example":     "15:00",
+					},
+				},
+			},
 			"AvailabilityRequest": map[string]interface{}{
 				"type":     "object",
 				"required": []string{"product_id", "quantity", "warehouse_location"},
 				"properties": map[string]interface{}{
 					"product_id": map[string]interface{}{
 						"type":        "string",
-						"description": "Unique identifier for the product",
+						"description": "Unique identifier for the product, or a bundle ID to check buildable bundle quantity",
 						"example":     "PROD-123",
 					},
 					"quantity": map[string]interface{}{
@@ -168,6 +464,23 @@ var openAPISpec = map[string]interface{}{
 						"example":     "DE-Berlin",
 						"enum":        []string{"DE-Berlin", "US-NewYork", "UK-London"},
 					},
+					"ship_date": map[string]interface{}{
+						"type":        "string",
+						"format":      "date",
+						"description": "Desired ship date. When set, inbound purchase orders expected by this date count towards availability (available-to-promise)",
+						"example":     "2026-11-02",
+					},
+					"channel": map[string]interface{}{
+						"type":        "string",
+						"description": "Sales channel. Where allocation rules apply, only the channel's allocation plus the shared pool can fill the request; a request without a channel can draw on all stock",
+						"enum":        []string{"web", "marketplace", "b2b"},
+						"example":     "web",
+					},
+					"unit": map[string]interface{}{
+						"type":        "string",
+						"description": "Unit the quantity is expressed in, one of the product's units in the catalog. Defaults to the product's base unit",
+						"example":     "case",
+					},
 				},
 			},
 			"AvailabilityResponse": map[string]interface{}{
@@ -175,7 +488,7 @@ var openAPISpec = map[string]interface{}{
 				"properties": map[string]interface{}{
 					"available": map[string]interface{}{
 						"type":        "boolean",
-						"description": "Whether the product is available in the requested quantity",
+						"description": "Whether the requested quantity can be ordered: true when status is available or backorderable",
 						"example":     true,
 					},
 					"available_quantity": map[string]interface{}{
@@ -183,6 +496,48 @@ var openAPISpec = map[string]interface{}{
 						"description": "Total quantity available after applying 10% reserve buffer",
 						"example":     90,
 					},
+					"status": map[string]interface{}{
+						"type":        "string",
+						"description": "Overall outcome. backorderable means stock falls short but the product's backorder policy accepts the remainder (pre-orders are backorderable too)",
+						"enum":        []string{"available", "backorderable", "unavailable"},
+						"example":     "available",
+					},
+					"unit": map[string]interface{}{
+						"type":        "string",
+						"description": "Unit of the requested quantity (catalog products only)",
+						"example":     "case",
+					},
+					"quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Requested quantity in the requested unit",
+						"example":     2,
+					},
+					"base_unit": map[string]interface{}{
+						"type":        "string",
+						"description": "Product's base unit of measure; all other quantities in the response are in this unit",
+						"example":     "each",
+					},
+					"base_quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Requested quantity converted to the base unit",
+						"example":     24,
+					},
+					"in_stock_quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Part of the requested quantity that can be fulfilled from stock",
+						"example":     5,
+					},
+					"backordered_quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Part of the requested quantity that would be placed on backorder",
+						"example":     0,
+					},
+					"reason_code": map[string]interface{}{
+						"type":        "string",
+						"description": "Machine-readable reason for the availability status",
+						"enum":        []string{"sufficient_stock", "insufficient_stock", "out_of_stock", "product_not_found", "unknown_warehouse", "source_unavailable", "source_timeout", "unknown_product", "discontinued", "pre_order", "pre_order_limit_exceeded", "backorderable", "unknown_unit"},
+						"example":     "sufficient_stock",
+					},
 					"reason": map[string]interface{}{
 						"type":        "string",
 						"description": "Detailed reason for the availability status",
@@ -193,6 +548,117 @@ var openAPISpec = map[string]interface{}{
 						"description": "Warehouse location that was checked",
 						"example":     "DE-Berlin",
 					},
+					"bundle": map[string]interface{}{
+						"type":        "object",
+						"description": "Component availability, present when a bundle was requested",
+						"properties": map[string]interface{}{
+							"bundle_id": map[string]interface{}{
+								"type":    "string",
+								"example": "BUNDLE-001",
+							},
+							"buildable_quantity": map[string]interface{}{
+								"type":    "integer",
+								"example": 11,
+							},
+							"limiting_component": map[string]interface{}{
+								"type":    "string",
+								"example": "PROD-456",
+							},
+							"components": map[string]interface{}{
+								"type": "array",
+								"items": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"product_id":          map[string]interface{}{"type": "string"},
+										"quantity_per_bundle": map[string]interface{}{"type": "integer"},
+										"available_quantity":  map[string]interface{}{"type": "integer"},
+										"buildable_quantity":  map[string]interface{}{"type": "integer"},
+									},
+								},
+							},
+						},
+					},
+					"substitutes": map[string]interface{}{
+						"type":        "array",
+						"description": "When unavailable: configured substitute products in stock at the same warehouse in the requested quantity, in order of preference",
+						"items": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"product_id": map[string]interface{}{
+									"type":    "string",
+									"example": "PROD-404",
+								},
+								"available_quantity": map[string]interface{}{
+									"type":    "integer",
+									"example": 180,
+								},
+							},
+						},
+					},
+					"channel_allocation": map[string]interface{}{
+						"type":        "object",
+						"description": "Stock the request's channel could draw on, present when allocation rules apply at the warehouse. available_quantity is computed from their sum",
+						"properties": map[string]interface{}{
+							"channel": map[string]interface{}{
+								"type":    "string",
+								"example": "web",
+							},
+							"allocated_quantity": map[string]interface{}{
+								"type":    "integer",
+								"example": 50,
+							},
+							"shared_quantity": map[string]interface{}{
+								"type":    "integer",
+								"example": 20,
+							},
+						},
+					},
+					"short_dated_quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Stock tracked by lot that was not counted because its lots expire before the ship date (today without one) plus the product's minimum shelf life",
+						"example":     20,
+					},
+					"earliest_available_date": map[string]interface{}{
+						"type":        "string",
+						"format":      "date",
+						"nullable":    true,
+						"description": "When unavailable: the earliest shipping day the quantity can ship, from inbound shipments or replenishment lead time, skipping weekends, warehouse holidays and a passed shipping cut-off. Null if no replenishment is scheduled",
+						"example":     "2026-11-02",
+					},
+					"ship_date": map[string]interface{}{
+						"type":        "string",
+						"format":      "date",
+						"description": "Ship date the request was checked against (available-to-promise only)",
+					},
+					"inbound_quantity": map[string]interface{}{
+						"type":        "integer",
+						"description": "Inbound units expected by the ship date (available-to-promise only)",
+						"example":     30,
+					},
+					"earliest_promise_date": map[string]interface{}{
+						"type":        "string",
+						"format":      "date",
+						"description": "Earliest date the full quantity can be promised (available-to-promise only); omitted if inbound stock never covers it",
+						"example":     "2026-11-02",
+					},
+					"source": map[string]interface{}{
+						"type":        "object",
+						"description": "Inventory source that answered and the age of its data",
+						"properties": map[string]interface{}{
+							"name": map[string]interface{}{
+								"type":    "string",
+								"example": "snapshot",
+							},
+							"as_of": map[string]interface{}{
+								"type":   "string",
+								"format": "date-time",
+							},
+							"age_seconds": map[string]interface{}{
+								"type":    "integer",
+								"example": 120,
+							},
+						},
+					},
 				},
 			},
 		},
@@ -202,6 +668,14 @@ var openAPISpec = map[string]interface{}{
 			"name":        "Availability",
 			"description": "Product availability checking operations",
 		},
+		{
+			"name":        "Warehouses",
+			"description": "Warehouse registry",
+		},
+		{
+			"name":        "Health",
+			"description": "Liveness and readiness probes and metrics",
+		},
 	},
 }
 
@@ -209,7 +683,7 @@ var openAPISpec = map[string]interface{}{
 func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	
+
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "  ")
 	encoder.Encode(openAPISpec)
@@ -219,7 +693,7 @@ func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
 func HandleSwaggerUI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	
+
 	html := `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -268,6 +742,6 @@ func HandleSwaggerUI(w http.ResponseWriter, r *http.Request) {
     </script>
 </body>
 </html>`
-	
+
 	w.Write([]byte(html))
 }
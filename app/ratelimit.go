@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteLimit is a token bucket per client: Rate requests per second on
+// average, with bursts of up to Burst requests
+type RouteLimit struct {
+	Rate  float64 `json:"rate"` // 0 leaves the route unlimited
+	Burst int     `json:"burst"`
+}
+
+// rateLimitSweepInterval is how often buckets that have refilled are dropped
+const rateLimitSweepInterval = time.Minute
+
+// RateLimiter limits how often each client may call a route. Clients are
+// told their limit in RateLimit-* headers and get 429 with Retry-After once
+// they exceed it. A nil *RateLimiter limits nothing
+type RateLimiter struct {
+	trustForwardedFor bool
+	clock             func() time.Time
+	tenants           *TenantRegistry // resolves tenant API keys; nil in single-tenant mode
+
+	mu     sync.Mutex
+	routes map[string]*routeLimiter
+}
+
+// routeLimiter holds the buckets of one route's clients
+type routeLimiter struct {
+	limit     RouteLimit
+	buckets   map[string]*tokenBucket
+	limited   uint64
+	lastSweep time.Time
+}
+
+// tokenBucket is one client's bucket; tokens refill continuously
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimitStats describes a route's limit for metrics
+type RateLimitStats struct {
+	Route   string
+	Limit   RouteLimit
+	Clients int    // clients tracked; refilled buckets are dropped within a minute
+	Limited uint64 // requests rejected
+}
+
+// NewRateLimiter creates the limiter for the configuration, or returns nil
+// when rate limiting is disabled
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &RateLimiter{
+		trustForwardedFor: cfg.TrustForwardedFor,
+		clock:             time.Now,
+		routes:            map[string]*routeLimiter{},
+	}
+}
+
+// SetTenants sets the registry that tenant API keys are resolved against
+func (l *RateLimiter) SetTenants(tenants *TenantRegistry) {
+	if l != nil {
+		l.tenants = tenants
+	}
+}
+
+// Limit applies limit to each client of route before passing requests to next
+func (l *RateLimiter) Limit(route string, limit RouteLimit, next http.Handler) http.Handler {
+	return l.limit(route, limit, (*RateLimiter).clientKey, next)
+}
+
+// LimitAddresses applies limit to each client IP of route, whatever
+// credentials its requests carry. It goes outside authentication, so
+// requests that fail to authenticate are limited too
+func (l *RateLimiter) LimitAddresses(route string, limit RouteLimit, next http.Handler) http.Handler {
+	return l.limit(route, limit, (*RateLimiter).addressKey, next)
+}
+
+// limit applies limit to each client of route, identified by key
+func (l *RateLimiter) limit(route string, limit RouteLimit, key func(*RateLimiter, *http.Request) string, next http.Handler) http.Handler {
+	if l == nil || limit.Rate <= 0 {
+		return next
+	}
+	l.mu.Lock()
+	rl := &routeLimiter{limit: limit, buckets: map[string]*tokenBucket{}, lastSweep: l.clock()}
+	l.routes[route] = rl
+	l.mu.Unlock()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, remaining, reset, retryAfter := l.take(rl, key(l, r))
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(reset))
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			addLogAttrs(r.Context(), slog.Bool("rate_limited", true))
+			http.Error(w, "Too many requests, retry in "+strconv.Itoa(retryAfter)+"s", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take spends a token from the client's bucket. It returns whether the
+// request may proceed, the whole tokens left, the seconds until the bucket
+// is full again and, when refused, the seconds until the next token
+func (l *RateLimiter) take(rl *routeLimiter, client string) (allowed bool, remaining, reset, retryAfter int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock()
+	limit := rl.limit
+	if now.Sub(rl.lastSweep) >= rateLimitSweepInterval {
+		rl.sweep(now)
+	}
+
+	b, ok := rl.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		rl.buckets[client] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*limit.Rate)
+	b.updated = now
+
+	allowed = b.tokens >= 1
+	if allowed {
+		b.tokens--
+	} else {
+		rl.limited++
+		retryAfter = int(math.Ceil((1 - b.tokens) / limit.Rate))
+	}
+	reset = int(math.Ceil((float64(limit.Burst) - b.tokens) / limit.Rate))
+	return allowed, int(b.tokens), reset, retryAfter
+}
+
+// sweep drops buckets that have refilled, as a new bucket would start full
+func (rl *routeLimiter) sweep(now time.Time) {
+	for client, b := range rl.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*rl.limit.Rate >= float64(rl.limit.Burst) {
+			delete(rl.buckets, client)
+		}
+	}
+	rl.lastSweep = now
+}
+
+// clientKey identifies who a request counts against: the authenticated
+// principal's subject, else its tenant API key, else the client IP. Each
+// key of a tenant is a client of its own. Keys that belong to no tenant
+// count against the IP, so made-up keys do not buy fresh buckets, and so do
+// principals without a subject
+func (l *RateLimiter) clientKey(r *http.Request) string {
+	if p := principalFromContext(r.Context()); p != nil && p.Subject != "" {
+		return "subject:" + p.Subject
+	}
+	key := r.Header.Get("X-API-Key")
+	if _, ok := l.tenants.lookupAPIKey(key); ok {
+		hash := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(hash[:])
+	}
+	return l.addressKey(r)
+}
+
+// addressKey identifies a request by its client IP. X-Forwarded-For is only
+// used when configured, as clients can set it themselves
+func (l *RateLimiter) addressKey(r *http.Request) string {
+	if l.trustForwardedFor {
+		// The proxy appends the address it saw; earlier entries are the client's word
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return "ip:" + ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// Stats returns every limited route's limit and activity, sorted by route
+func (l *RateLimiter) Stats() []RateLimitStats {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := make([]RateLimitStats, 0, len(l.routes))
+	for route, rl := range l.routes {
+		stats = append(stats, RateLimitStats{Route: route, Limit: rl.limit, Clients: len(rl.buckets), Limited: rl.limited})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns an enabled limiter with a controllable clock
+func newTestLimiter(trustForwardedFor bool) (*RateLimiter, *time.Time) {
+	limiter := NewRateLimiter(RateLimitConfig{Enabled: true, TrustForwardedFor: trustForwardedFor})
+	clock := now()
+	limiter.clock = func() time.Time { return clock }
+	return limiter, &clock
+}
+
+// testTenants returns a registry where retail-key and retail-key-2 belong
+// to the retail tenant
+func testTenants() *TenantRegistry {
+	retail := &Tenant{ID: "retail"}
+	return &TenantRegistry{byAPIKey: map[apiKeyHash]*Tenant{
+		sha256.Sum256([]byte("retail-key")):   retail,
+		sha256.Sum256([]byte("retail-key-2")): retail,
+	}}
+}
+
+// limitedRequest serves one request from remoteAddr through handler
+func limitedRequest(handler http.Handler, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+	req.RemoteAddr = remoteAddr
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimiter_RejectsOnceBurstIsSpent(t *testing.T) {
+	limiter, clock := newTestLimiter(false)
+	handler := limiter.Limit("/api/warehouses", RouteLimit{Rate: 0.5, Burst: 2}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rr := limitedRequest(handler, "192.0.2.1:1234", nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200, got %d", i+1, rr.Code)
+		}
+		if got := rr.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("Request %d: expected RateLimit-Remaining %s, got %s", i+1, wantRemaining, got)
+		}
+	}
+
+	rr := limitedRequest(handler, "192.0.2.1:1234", nil)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", rr.Code)
+	}
+	wantHeaders := map[string]string{
+		"Retry-After":         "2",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "4",
+	}
+	for key, want := range wantHeaders {
+		if got := rr.Header().Get(key); got != want {
+			t.Errorf("Expected %s %s, got %q", key, want, got)
+		}
+	}
+
+	// One token refills every two seconds
+	*clock = clock.Add(2 * time.Second)
+	if rr := limitedRequest(handler, "192.0.2.1:1234", nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected a refilled token to be accepted, got %d", rr.Code)
+	}
+	if rr := limitedRequest(handler, "192.0.2.1:1234", nil); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 once the refilled token is spent, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiter_ClientKey(t *testing.T) {
+	tests := []struct {
+		name              string
+		trustForwardedFor bool
+		remoteAddr        string
+		header            map[string]string
+		principal         *Principal
+		want              string
+	}{
+		{"remote address", false, "192.0.2.1:1234", nil, nil, "ip:192.0.2.1"},
+		{"forwarded for ignored by default", false, "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "198.51.100.7"}, nil, "ip:192.0.2.1"},
+		{"forwarded for trusted", true, "192.0.2.1:1234", map[string]string{"X-Forwarded-For": "203.0.113.9, 198.51.100.7"}, nil, "ip:198.51.100.7"},
+		{"tenant API key", true, "192.0.2.1:1234", map[string]string{"X-API-Key": "retail-key", "X-Forwarded-For": "198.51.100.7"}, nil, "key:" + hashAPIKey("retail-key")},
+		{"API key of no tenant", true, "192.0.2.1:1234", map[string]string{"X-API-Key": "made-up-key", "X-Forwarded-For": "198.51.100.7"}, nil, "ip:198.51.100.7"},
+		{"authenticated principal", false, "192.0.2.1:1234", map[string]string{"X-API-Key": "retail-key"}, &Principal{Subject: "svc"}, "subject:svc"},
+		{"principal without a subject", false, "192.0.2.1:1234", nil, &Principal{Scopes: []string{ScopeAvailabilityRead}}, "ip:192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, _ := newTestLimiter(tt.trustForwardedFor)
+			limiter.SetTenants(testTenants())
+			req := httptest.NewRequest(http.MethodGet, "/api/warehouses", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			if tt.principal != nil {
+				req = req.WithContext(context.WithValue(req.Context(), principalKey{}, tt.principal))
+			}
+			if got := limiter.clientKey(req); got != tt.want {
+				t.Errorf("Expected key %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_ClientsHaveSeparateBuckets(t *testing.T) {
+	limiter, _ := newTestLimiter(false)
+	limiter.SetTenants(testTenants())
+	handler := limiter.Limit("/api/warehouses", RouteLimit{Rate: 1, Burst: 1}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	limitedRequest(handler, "192.0.2.1:1234", nil)
+	if rr := limitedRequest(handler, "192.0.2.1:1234", nil); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the first client to be limited, got %d", rr.Code)
+	}
+	if rr := limitedRequest(handler, "192.0.2.2:1234", nil); rr.Code != http.StatusOK {
+		t.Errorf("Expected another address to have its own bucket, got %d", rr.Code)
+	}
+	if rr := limitedRequest(handler, "192.0.2.1:1234", map[string]string{"X-API-Key": "retail-key"}); rr.Code != http.StatusOK {
+		t.Errorf("Expected a tenant API key to have its own bucket, got %d", rr.Code)
+	}
+	if rr := limitedRequest(handler, "192.0.2.1:1234", map[string]string{"X-API-Key": "retail-key-2"}); rr.Code != http.StatusOK {
+		t.Errorf("Expected another key of the same tenant to have its own bucket, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiter_UnknownAPIKeysShareTheIPBucket(t *testing.T) {
+	limiter, _ := newTestLimiter(false)
+	limiter.SetTenants(testTenants())
+	handler := limiter.Limit("/api/warehouses", RouteLimit{Rate: 1, Burst: 2}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// A fresh made-up key per request must not reset the limit
+	var rr *httptest.ResponseRecorder
+	for i := range 3 {
+		rr = limitedRequest(handler, "192.0.2.1:1234", map[string]string{"X-API-Key": fmt.Sprintf("guess-%d", i)})
+	}
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected made-up API keys to be limited by IP, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiter_LimitAddressesCoversFailedAuthentication(t *testing.T) {
+	keys, err := NewAPIKeyAuthenticator([]APIKey{{Name: "storefront", SHA256: hashAPIKey("storefront-secret"), Scopes: []string{ScopeAvailabilityRead}}})
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	limiter, _ := newTestLimiter(false)
+	handler := limiter.LimitAddresses("/api/", RouteLimit{Rate: 1, Burst: 2}, NewAuth(keys).Require(ScopeAvailabilityRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	// Each guess is a new credential, but all of them come from one address
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		rr := limitedRequest(handler, "192.0.2.1:1234", map[string]string{"Authorization": fmt.Sprintf("Bearer guess-%d", i)})
+		if rr.Code != want {
+			t.Errorf("Request %d: expected status %d, got %d", i+1, want, rr.Code)
+		}
+	}
+	if rr := limitedRequest(handler, "192.0.2.2:1234", map[string]string{"Authorization": "Bearer storefront-secret"}); rr.Code != http.StatusOK {
+		t.Errorf("Expected another address to be served, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiter_SweepsRefilledBuckets(t *testing.T) {
+	limiter, clock := newTestLimiter(false)
+	handler := limiter.Limit("/api/warehouses", RouteLimit{Rate: 1, Burst: 5}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	limitedRequest(handler, "192.0.2.1:1234", nil)
+	limitedRequest(handler, "192.0.2.2:1234", nil)
+	if stats := limiter.Stats(); stats[0].Clients != 2 {
+		t.Fatalf("Expected 2 clients tracked, got %d", stats[0].Clients)
+	}
+
+	*clock = clock.Add(rateLimitSweepInterval)
+	limitedRequest(handler, "192.0.2.3:1234", nil)
+	if stats := limiter.Stats(); stats[0].Clients != 1 {
+		t.Errorf("Expected refilled buckets to be dropped, got %d clients", stats[0].Clients)
+	}
+}
+
+func TestRateLimiter_DisabledLimitsNothing(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *RateLimiter
+		limit   RouteLimit
+	}{
+		{"disabled", NewRateLimiter(DefaultConfig().RateLimit), RouteLimit{Rate: 1, Burst: 1}},
+		{"unlimited route", NewRateLimiter(RateLimitConfig{Enabled: true}), RouteLimit{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := tt.limiter.Limit("/api/warehouses", tt.limit, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			for range 3 {
+				rr := limitedRequest(handler, "192.0.2.1:1234", nil)
+				if rr.Code != http.StatusOK || rr.Header().Get("RateLimit-Limit") != "" {
+					t.Fatalf("Expected an unlimited 200, got %d with RateLimit-Limit %q", rr.Code, rr.Header().Get("RateLimit-Limit"))
+				}
+			}
+			if stats := tt.limiter.Stats(); len(stats) != 0 {
+				t.Errorf("Expected no limited routes, got %v", stats)
+			}
+		})
+	}
+}
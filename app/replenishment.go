@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ReplenishmentRule is the lead time to restock a product. An empty warehouse
+// applies to every warehouse without a rule of its own
+type ReplenishmentRule struct {
+	ProductID    string `json:"product_id"`
+	Warehouse    string `json:"warehouse,omitempty"`
+	LeadTimeDays int    `json:"lead_time_days"`
+}
+
+// ReplenishmentPlan holds replenishment lead times, loaded from a JSON file
+type ReplenishmentPlan struct {
+	filePath string
+	rules    map[stockKey]ReplenishmentRule
+}
+
+// NewReplenishmentPlan creates a plan backed by the given JSON file
+func NewReplenishmentPlan(filePath string) *ReplenishmentPlan {
+	return &ReplenishmentPlan{
+		filePath: filePath,
+		rules:    map[stockKey]ReplenishmentRule{},
+	}
+}
+
+// Load reads and validates the replenishment rules
+func (p *ReplenishmentPlan) Load() error {
+	data, err := os.ReadFile(p.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read replenishment plan: %w", err)
+	}
+
+	var rules []ReplenishmentRule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return fmt.Errorf("failed to parse replenishment plan JSON: %w", err)
+	}
+
+	return p.setRules(rules)
+}
+
+// setRules validates rules and replaces the plan with them
+func (p *ReplenishmentPlan) setRules(rules []ReplenishmentRule) error {
+	byKey := make(map[stockKey]ReplenishmentRule, len(rules))
+	for _, rule := range rules {
+		if rule.ProductID == "" {
+			return fmt.Errorf("replenishment rule without product_id in %s", p.filePath)
+		}
+		if rule.LeadTimeDays < 0 {
+			return fmt.Errorf("replenishment rule for %s: lead_time_days must not be negative", rule.ProductID)
+		}
+		key := stockKey{rule.ProductID, rule.Warehouse}
+		if _, ok := byKey[key]; ok {
+			return fmt.Errorf("duplicate replenishment rule for %s at %q", rule.ProductID, rule.Warehouse)
+		}
+		byKey[key] = rule
+	}
+
+	p.rules = byKey
+	return nil
+}
+
+// LeadTime returns the replenishment lead time in days for a product at a
+// warehouse, falling back to the product's warehouse-independent rule
+func (p *ReplenishmentPlan) LeadTime(productID, warehouse string) (int, bool) {
+	if rule, ok := p.rules[stockKey{productID, warehouse}]; ok {
+		return rule.LeadTimeDays, true
+	}
+	if rule, ok := p.rules[stockKey{productID, ""}]; ok {
+		return rule.LeadTimeDays, true
+	}
+	return 0, false
+}
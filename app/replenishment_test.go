@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newEstimateService returns a service over the mock adapter with the given
+// lead times and a DE-Berlin warehouse with the given holidays and cut-off
+func newEstimateService(t *testing.T, rules []ReplenishmentRule, inbound []InboundShipment, holidays []string, cutoff string) *AvailabilityService {
+	t.Helper()
+	plan := NewReplenishmentPlan("")
+	if err := plan.setRules(rules); err != nil {
+		t.Fatalf("Failed to set rules: %v", err)
+	}
+	schedule := NewInboundSchedule("")
+	if err := schedule.setShipments(inbound); err != nil {
+		t.Fatalf("Failed to set shipments: %v", err)
+	}
+	registry := NewWarehouseRegistry("")
+	err := registry.setWarehouses([]Warehouse{
+		{Code: "DE-Berlin", TimeZone: "UTC", Active: true, ShippingCutoff: cutoff, Holidays: holidays},
+	})
+	if err != nil {
+		t.Fatalf("Failed to set warehouses: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(),
+		WithReplenishmentPlan(plan),
+		WithInboundSchedule(schedule),
+		WithWarehouseRegistry(registry),
+	)
+}
+
+// earliestDate returns the response's earliest_available_date or "null"
+func earliestDate(resp Response) string {
+	if resp.EarliestAvailableDate == nil {
+		return "null"
+	}
+	return *resp.EarliestAvailableDate
+}
+
+// insufficientRequest asks for more PROD-456 than the 23 units available at DE-Berlin
+var insufficientRequest = Request{ProductID: "PROD-456", Quantity: 30, WarehouseLocation: "DE-Berlin"}
+
+func TestEarliestAvailableDate_FromLeadTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		leadTime int
+		holidays []string
+		expected string
+	}{
+		{"weekday", 5, nil, "2024-01-15"},          // Wednesday + 5 = Monday
+		{"lands on weekend", 3, nil, "2024-01-15"}, // Saturday moves to Monday
+		{"lands on holiday", 5, []string{"2024-01-15"}, "2024-01-16"},
+	}
+
+	for _, tt := range tests {
+		service := newEstimateService(t, []ReplenishmentRule{{ProductID: "PROD-456", LeadTimeDays: tt.leadTime}}, nil, tt.holidays, "")
+		resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+		if resp.Available {
+			t.Fatalf("%s: expected available=false", tt.name)
+		}
+		if got := earliestDate(resp); got != tt.expected {
+			t.Errorf("%s: expected earliest_available_date=%s, got %s", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestEarliestAvailableDate_PrefersEarlierInbound(t *testing.T) {
+	inbound := []InboundShipment{{PurchaseOrder: "PO-1", ProductID: "PROD-456", Warehouse: "DE-Berlin", Quantity: 10, ExpectedDate: "2024-01-12"}}
+	service := newEstimateService(t, []ReplenishmentRule{{ProductID: "PROD-456", LeadTimeDays: 14}}, inbound, nil, "")
+
+	// 25 + 10 = 35, 35 - 3 = 32 covers 30 units on Friday 2024-01-12
+	resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+	if got := earliestDate(resp); got != "2024-01-12" {
+		t.Errorf("Expected earliest_available_date=2024-01-12, got %s", got)
+	}
+}
+
+func TestEarliestAvailableDate_NullWithoutReplenishment(t *testing.T) {
+	service := newEstimateService(t, nil, nil, nil, "")
+
+	resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+	data, _ := json.Marshal(resp)
+	if !strings.Contains(string(data), `"earliest_available_date":null`) {
+		t.Errorf("Expected earliest_available_date to be null, got %s", data)
+	}
+}
+
+func TestEarliestAvailableDate_AfterShippingCutoff(t *testing.T) {
+	// Lead time 0 means today, but the 11:00 cut-off has passed at 12:00
+	service := newEstimateService(t, []ReplenishmentRule{{ProductID: "PROD-456", LeadTimeDays: 0}}, nil, nil, "11:00")
+
+	resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+	if got := earliestDate(resp); got != "2024-01-11" {
+		t.Errorf("Expected earliest_available_date=2024-01-11, got %s", got)
+	}
+}
+
+func TestEarliestAvailableDate_MidnightCutoff(t *testing.T) {
+	// A 00:00 cut-off has always passed, so nothing ships the same day
+	service := newEstimateService(t, []ReplenishmentRule{{ProductID: "PROD-456", LeadTimeDays: 0}}, nil, nil, "00:00")
+
+	resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+	if got := earliestDate(resp); got != "2024-01-11" {
+		t.Errorf("Expected earliest_available_date=2024-01-11, got %s", got)
+	}
+}
+
+func TestEarliestAvailableDate_UsesWarehouseLocalDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		timeZone string
+		cutoff   string
+		leadTime int
+		want     string
+	}{
+		// 20:00 Wednesday in New York is already Thursday in UTC
+		{"warehouse behind UTC", time.Date(2024, time.January, 11, 1, 0, 0, 0, time.UTC), "America/New_York", "17:00", 1, "2024-01-11"},
+		{"before local cut-off", time.Date(2024, time.January, 10, 15, 0, 0, 0, time.UTC), "America/New_York", "17:00", 0, "2024-01-10"},
+		// 02:00 Thursday in Tokyo is past its 01:00 cut-off while still Wednesday in UTC
+		{"warehouse ahead of UTC past cut-off", time.Date(2024, time.January, 10, 17, 0, 0, 0, time.UTC), "Asia/Tokyo", "01:00", 0, "2024-01-12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restore := now
+			now = func() time.Time { return tt.now }
+			defer func() { now = restore }()
+
+			service := newEstimateService(t, []ReplenishmentRule{{ProductID: "PROD-456", LeadTimeDays: tt.leadTime}}, nil, nil, "")
+			err := service.warehouses.setWarehouses([]Warehouse{
+				{Code: "DE-Berlin", TimeZone: tt.timeZone, Active: true, ShippingCutoff: tt.cutoff},
+			})
+			if err != nil {
+				t.Fatalf("Failed to set warehouses: %v", err)
+			}
+
+			resp := service.CheckAvailability(t.Context(), insufficientRequest)
+
+			if got := earliestDate(resp); got != tt.want {
+				t.Errorf("Expected earliest_available_date=%s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEarliestAvailableDate_WeekendRule(t *testing.T) {
+	saturday := time.Date(2024, time.January, 13, 12, 0, 0, 0, time.UTC)
+	restore := now
+	now = func() time.Time { return saturday }
+	defer func() { now = restore }()
+
+	service := newEstimateService(t, nil, nil, nil, "")
+
+	// 50 units need 100 in stock on a weekend but only 90 are available;
+	// on Monday the normal rule applies and stock on hand covers the order
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 50, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available {
+		t.Fatal("Expected available=false on a weekend")
+	}
+	if got := earliestDate(resp); got != "2024-01-15" {
+		t.Errorf("Expected earliest_available_date=2024-01-15, got %s", got)
+	}
+}
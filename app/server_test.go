@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// startTestServer serves handler on a random local port until the returned
+// cancel function is called; the result of serve arrives on the channel
+func startTestServer(t *testing.T, handler http.Handler, drainTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- serve(ctx, newServer(DefaultConfig().Server, "", handler), listener, drainTimeout)
+	}()
+	return "http://" + listener.Addr().String(), cancel, done
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	url, cancel, done := startTestServer(t, handler, 5*time.Second)
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+
+	// New connections are refused while the in-flight request drains
+	time.Sleep(50 * time.Millisecond)
+	if _, err := http.Get(url); err == nil {
+		t.Error("Expected new requests to be refused during shutdown")
+	}
+
+	close(release)
+	if r := <-responses; r.err != nil || r.body != "done" {
+		t.Errorf("Expected the in-flight request to complete, got body=%q err=%v", r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestServe_DrainDeadline(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+	url, cancel, done := startTestServer(t, handler, 50*time.Millisecond)
+
+	go http.Get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected an error when requests outlive the drain deadline")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve did not return after the drain deadline")
+	}
+}
+
+func TestWithRoute_ResolvesBeforeHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/api/", &StartupGate{})
+	mux.Handle("/api/warehouses", &StartupGate{})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/warehouses", "/api/warehouses"},
+		{"/api/other", "/api/"},
+		{"/unknown", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var got string
+			record := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = routeFromContext(r.Context())
+				mux.ServeHTTP(w, r)
+			})
+			withRoute(mux, record).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got != tt.want {
+				t.Errorf("Expected route %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SubstitutionMap lists, per product, alternatives to offer when it is
+// unavailable, in order of preference. Loaded from a JSON object mapping
+// product IDs to arrays of product IDs
+type SubstitutionMap struct {
+	filePath    string
+	substitutes map[string][]string
+}
+
+// NewSubstitutionMap creates a substitution map backed by the given JSON file
+func NewSubstitutionMap(filePath string) *SubstitutionMap {
+	return &SubstitutionMap{
+		filePath:    filePath,
+		substitutes: map[string][]string{},
+	}
+}
+
+// Load reads and validates the substitution map
+func (m *SubstitutionMap) Load() error {
+	data, err := os.ReadFile(m.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read substitution map: %w", err)
+	}
+
+	var substitutes map[string][]string
+	err = json.Unmarshal(data, &substitutes)
+	if err != nil {
+		return fmt.Errorf("failed to parse substitution map JSON: %w", err)
+	}
+
+	for productID, alternatives := range substitutes {
+		for _, alternative := range alternatives {
+			if alternative == productID {
+				return fmt.Errorf("product %s lists itself as a substitute", productID)
+			}
+		}
+	}
+
+	m.substitutes = substitutes
+	return nil
+}
+
+// Substitutes returns the alternatives for a product in order of preference
+func (m *SubstitutionMap) Substitutes(productID string) []string {
+	return m.substitutes[productID]
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+)
+
+// newSubstituteService returns a service over the mock adapter with the shipped substitutes.json
+func newSubstituteService(t *testing.T) *AvailabilityService {
+	t.Helper()
+	substitutions := NewSubstitutionMap("substitutes.json")
+	if err := substitutions.Load(); err != nil {
+		t.Fatalf("Failed to load substitution map: %v", err)
+	}
+	return NewAvailabilityService(NewMockInventoryAdapter(), WithSubstitutionMap(substitutions))
+}
+
+func TestCheckAvailability_SuggestsAvailableSubstitutes(t *testing.T) {
+	service := newSubstituteService(t)
+
+	// PROD-123 has 90 units after reserve; substitutes are PROD-505 (not stocked
+	// in DE-Berlin) and PROD-404 (180 after reserve)
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 95, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Available {
+		t.Fatal("Expected available=false")
+	}
+	if len(resp.Substitutes) != 1 {
+		t.Fatalf("Expected 1 substitute, got %+v", resp.Substitutes)
+	}
+	if sub := resp.Substitutes[0]; sub.ProductID != "PROD-404" || sub.AvailableQuantity != 180 {
+		t.Errorf("Expected PROD-404 with 180 available, got %+v", sub)
+	}
+}
+
+func TestCheckAvailability_NoSubstitutesWhenAvailable(t *testing.T) {
+	service := newSubstituteService(t)
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+
+	if !resp.Available {
+		t.Fatalf("Expected available=true, got false. Reason: %s", resp.Reason)
+	}
+	if resp.Substitutes != nil {
+		t.Errorf("Expected no substitutes for an available product, got %+v", resp.Substitutes)
+	}
+}
+
+func TestCheckAvailability_SubstitutesNeedRequestedQuantity(t *testing.T) {
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithSubstitutionMap(&SubstitutionMap{
+		substitutes: map[string][]string{"PROD-789": {"PROD-505", "PROD-123"}},
+	}))
+
+	// PROD-505 has only 4 units after reserve in US-NewYork; PROD-123 has 45
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-789", Quantity: 20, WarehouseLocation: "US-NewYork"})
+
+	if len(resp.Substitutes) != 1 || resp.Substitutes[0].ProductID != "PROD-123" {
+		t.Errorf("Expected only PROD-123 as substitute, got %+v", resp.Substitutes)
+	}
+}
+
+func TestCheckAvailability_SubstitutesNeedSameBaseUnit(t *testing.T) {
+	catalog := &ProductCatalog{products: map[string]Product{
+		"PROD-123": {ID: "PROD-123", Status: ProductActive, UnitOfMeasure: "each"},
+		"PROD-404": {ID: "PROD-404", Status: ProductActive, UnitOfMeasure: "ream"},
+		"PROD-456": {ID: "PROD-456", Status: ProductActive, UnitOfMeasure: "each"},
+	}}
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithProductCatalog(catalog), WithSubstitutionMap(&SubstitutionMap{
+		substitutes: map[string][]string{"PROD-123": {"PROD-404", "PROD-456"}},
+	}))
+
+	// 95 each of PROD-123 are not 95 of the 180 reams of PROD-404
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 95, WarehouseLocation: "DE-Berlin"})
+
+	if len(resp.Substitutes) != 0 {
+		t.Errorf("Expected no substitute in another unit, got %+v", resp.Substitutes)
+	}
+
+	// PROD-456, counted in each like PROD-123, still qualifies
+	resp = service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 60, WarehouseLocation: "US-NewYork"})
+	if len(resp.Substitutes) != 1 || resp.Substitutes[0].ProductID != "PROD-456" {
+		t.Errorf("Expected only PROD-456 as substitute, got %+v", resp.Substitutes)
+	}
+}
+
+func TestCheckAvailability_NoSubstitutesWhenSourceFails(t *testing.T) {
+	service := NewAvailabilityService(NewCompositeInventoryAdapter(
+		InventorySource{Name: "api", Adapter: &UnavailableInventoryAdapter{}},
+	), WithSubstitutionMap(&SubstitutionMap{
+		substitutes: map[string][]string{"PROD-123": {"PROD-404"}},
+	}))
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 5, WarehouseLocation: "DE-Berlin"})
+
+	if resp.Substitutes != nil {
+		t.Errorf("Expected no substitutes when the source is unavailable, got %+v", resp.Substitutes)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Tenant is a business unit sharing the service. Each tenant has its own data
+// directory holding its inventory and rule files, and is identified by one of
+// its API keys or, if it has none, by the X-Tenant-ID header. Like the
+// authentication keys, only the hex SHA-256 of each key is stored
+type Tenant struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	DataDir string   `json:"data_dir"`           // relative to the tenants file
+	APIKeys []string `json:"api_keys,omitempty"` // hex SHA-256 of each key
+
+	// InventoryAPIURL is the tenant's own inventory API, required with the api adapter
+	InventoryAPIURL string `json:"inventory_api_url,omitempty"`
+
+	dataset *Dataset
+	handler *AvailabilityHandler
+}
+
+// TenantRegistry holds the tenants served by this instance, loaded from a JSON file
+type TenantRegistry struct {
+	filePath  string
+	inventory InventoryConfig
+	options   []ServiceOption
+	tenants   map[string]*Tenant
+	byAPIKey  map[apiKeyHash]*Tenant
+}
+
+// NewTenantRegistry creates a registry backed by the given JSON file. Every
+// tenant's dataset uses the inventory config and service options given
+func NewTenantRegistry(filePath string, inventory InventoryConfig, options ...ServiceOption) *TenantRegistry {
+	return &TenantRegistry{
+		filePath:  filePath,
+		inventory: inventory,
+		options:   options,
+		tenants:   map[string]*Tenant{},
+		byAPIKey:  map[apiKeyHash]*Tenant{},
+	}
+}
+
+// Load reads the tenant definitions and loads every tenant's dataset
+func (r *TenantRegistry) Load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read tenants file: %w", err)
+	}
+
+	var tenants []*Tenant
+	err = json.Unmarshal(data, &tenants)
+	if err != nil {
+		return fmt.Errorf("failed to parse tenants JSON: %w", err)
+	}
+
+	return r.setTenants(tenants)
+}
+
+// setTenants validates tenants, loads their datasets and replaces the registry
+// with them. Data directories may not be shared or nested, so no two tenants
+// can ever read the same inventory or rules
+func (r *TenantRegistry) setTenants(tenants []*Tenant) error {
+	byID := make(map[string]*Tenant, len(tenants))
+	byAPIKey := map[apiKeyHash]*Tenant{}
+	dirs := map[string]string{}
+	apiURLs := map[string]string{}
+	for _, t := range tenants {
+		if t.ID == "" || t.DataDir == "" {
+			return fmt.Errorf("tenants need an id and a data_dir in %s", r.filePath)
+		}
+		if _, ok := byID[t.ID]; ok {
+			return fmt.Errorf("duplicate tenant %s", t.ID)
+		}
+		byID[t.ID] = t
+
+		dir := filepath.Clean(filepath.Join(filepath.Dir(r.filePath), t.DataDir))
+		for other, owner := range dirs {
+			if isWithin(dir, other) || isWithin(other, dir) {
+				return fmt.Errorf("tenants %s and %s share data directory %s", owner, t.ID, dir)
+			}
+		}
+		dirs[dir] = t.ID
+
+		// A shared inventory API would answer for every tenant alike
+		if r.inventory.Adapter == AdapterAPI {
+			if t.InventoryAPIURL == "" {
+				return fmt.Errorf("tenant %s: the api inventory adapter needs an inventory_api_url per tenant", t.ID)
+			}
+			if owner, ok := apiURLs[t.InventoryAPIURL]; ok {
+				return fmt.Errorf("tenants %s and %s share inventory API %s", owner, t.ID, t.InventoryAPIURL)
+			}
+			apiURLs[t.InventoryAPIURL] = t.ID
+		}
+
+		for _, key := range t.APIKeys {
+			hash, err := parseAPIKeyHash(key)
+			if err != nil {
+				return fmt.Errorf("tenant %s: API key %w", t.ID, err)
+			}
+			if owner, ok := byAPIKey[hash]; ok {
+				return fmt.Errorf("tenants %s and %s share an API key", owner.ID, t.ID)
+			}
+			byAPIKey[hash] = t
+		}
+	}
+
+	var loaded []*Tenant
+	for dir, id := range dirs {
+		t := byID[id]
+		inventory := r.inventory
+		inventory.APIURL = t.InventoryAPIURL
+		options := append(append([]ServiceOption(nil), r.options...), WithTenant(t.ID))
+		dataset, err := LoadDataset(dir, inventory, options...)
+		if err != nil {
+			for _, t := range loaded {
+				t.dataset.Close()
+			}
+			return fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		t.dataset = dataset
+		t.handler = NewAvailabilityHandler(dataset.Service, dataset.Warehouses)
+		loaded = append(loaded, t)
+	}
+
+	r.Close()
+	r.tenants = byID
+	r.byAPIKey = byAPIKey
+	return nil
+}
+
+// lookupAPIKey returns the tenant an API key belongs to. A nil registry knows no keys
+func (r *TenantRegistry) lookupAPIKey(key string) (*Tenant, bool) {
+	if r == nil || key == "" {
+		return nil, false
+	}
+	t, ok := r.byAPIKey[sha256.Sum256([]byte(key))]
+	return t, ok
+}
+
+// isWithin reports whether path is dir or inside it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// List returns every tenant ordered by ID
+func (r *TenantRegistry) List() []*Tenant {
+	tenants := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// Checks returns a health check of each tenant's inventory source, named
+// "inventory:<tenant ID>"
+func (r *TenantRegistry) Checks() []func() HealthCheck {
+	var checks []func() HealthCheck
+	for _, t := range r.List() {
+		monitor := t.dataset.Monitor
+		checks = append(checks, func() HealthCheck {
+			check := monitor.Check()
+			check.Name = "inventory:" + t.ID
+			return check
+		})
+	}
+	return checks
+}
+
+// Close releases every tenant's dataset
+func (r *TenantRegistry) Close() {
+	for _, t := range r.tenants {
+		if t.dataset != nil {
+			t.dataset.Close()
+		}
+	}
+}
+
+// Resolve identifies the tenant a request is made for. An X-API-Key header
+// decides the tenant; an X-Tenant-ID header alongside it must agree. Without
+// an API key, X-Tenant-ID is only accepted for tenants that have no keys.
+// On failure it returns the HTTP status and message to reply with
+func (r *TenantRegistry) Resolve(req *http.Request) (*Tenant, int, string) {
+	tenantID := req.Header.Get("X-Tenant-ID")
+
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		t, ok := r.lookupAPIKey(key)
+		if !ok {
+			return nil, http.StatusUnauthorized, "invalid API key"
+		}
+		if tenantID != "" && tenantID != t.ID {
+			return nil, http.StatusForbidden, "API key does not belong to tenant " + tenantID
+		}
+		return t, http.StatusOK, ""
+	}
+
+	if tenantID == "" {
+		return nil, http.StatusUnauthorized, "X-API-Key or X-Tenant-ID header is required"
+	}
+	t, ok := r.tenants[tenantID]
+	if !ok {
+		return nil, http.StatusNotFound, "unknown tenant " + tenantID
+	}
+	if len(t.APIKeys) > 0 {
+		return nil, http.StatusUnauthorized, "tenant " + tenantID + " requires an API key"
+	}
+	return t, http.StatusOK, ""
+}
+
+// Handler returns an http.HandlerFunc that resolves the tenant and passes the
+// request on to route with that tenant's AvailabilityHandler, e.g.
+//
+//	tenants.Handler((*AvailabilityHandler).HandleCheckAvailability)
+//
+// Requests authenticated with credentials limited to a tenant are refused
+// for any other tenant
+func (r *TenantRegistry) Handler(route func(*AvailabilityHandler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		t, status, msg := r.Resolve(req)
+		if t == nil {
+			http.Error(w, msg, status)
+			return
+		}
+		if p := principalFromContext(req.Context()); p != nil && p.Tenant != "" && p.Tenant != t.ID {
+			http.Error(w, "credentials do not grant access to tenant "+t.ID, http.StatusForbidden)
+			return
+		}
+		route(t.handler, w, req.WithContext(withTenant(req.Context(), t.ID)))
+	}
+}
+
+// tenantContextKey is the context key for the resolved tenant ID
+type tenantContextKey struct{}
+
+// withTenant returns a context carrying the resolved tenant ID
+func withTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// tenantFromContext returns the tenant ID resolved for a request, or "" in single-tenant mode
+func tenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenantID
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTenantDir creates a tenant data directory under root holding the
+// shipped rule files and the given inventory
+func writeTenantDir(t *testing.T, root, name, inventory string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", dir, err)
+	}
+	for _, file := range []string{"warehouses.json", "products.json", "inbound.json", "replenishment.json", "bundles.json", "substitutes.json", "allocations.json"} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), data, 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "inventory.json"), []byte(inventory), 0o644); err != nil {
+		t.Fatalf("failed to write inventory: %v", err)
+	}
+}
+
+// newTestTenants loads two tenants: retail (key "retail-key") stocks only
+// PROD-123 and wholesale (no key) stocks only PROD-456
+func newTestTenants(t *testing.T) *TenantRegistry {
+	t.Helper()
+	root := t.TempDir()
+	writeTenantDir(t, root, "retail", `[{"product_id": "PROD-123", "warehouse": "DE-Berlin", "stock_level": 100}]`)
+	writeTenantDir(t, root, "wholesale", `[{"product_id": "PROD-456", "warehouse": "DE-Berlin", "stock_level": 40}]`)
+
+	path := filepath.Join(root, "tenants.json")
+	os.WriteFile(path, []byte(`[
+		{"id": "retail", "data_dir": "retail", "api_keys": ["`+hashAPIKey("retail-key")+`"]},
+		{"id": "wholesale", "data_dir": "wholesale"}
+	]`), 0o644)
+
+	tenants := NewTenantRegistry(path, DefaultConfig().Inventory)
+	if err := tenants.Load(); err != nil {
+		t.Fatalf("Failed to load tenants: %v", err)
+	}
+	t.Cleanup(tenants.Close)
+	return tenants
+}
+
+// postTenantAvailability posts a check for productID with the given headers
+func postTenantAvailability(tenants *TenantRegistry, productID string, headers map[string]string) *httptest.ResponseRecorder {
+	body := `{"product_id": "` + productID + `", "quantity": 1, "warehouse_location": "DE-Berlin"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	tenants.Handler((*AvailabilityHandler).HandleCheckAvailability)(rec, req)
+	return rec
+}
+
+func TestTenants_InventoryIsIsolated(t *testing.T) {
+	tenants := newTestTenants(t)
+
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		productID string
+		expected  int
+	}{
+		{"retail sees its own stock", map[string]string{"X-API-Key": "retail-key"}, "PROD-123", http.StatusOK},
+		{"retail cannot see wholesale stock", map[string]string{"X-API-Key": "retail-key"}, "PROD-456", http.StatusNotFound},
+		{"wholesale sees its own stock", map[string]string{"X-Tenant-ID": "wholesale"}, "PROD-456", http.StatusOK},
+		{"wholesale cannot see retail stock", map[string]string{"X-Tenant-ID": "wholesale"}, "PROD-123", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		rec := postTenantAvailability(tenants, tt.productID, tt.headers)
+		if rec.Code != tt.expected {
+			t.Errorf("%s: expected HTTP %d, got %d: %s", tt.name, tt.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTenants_Identification(t *testing.T) {
+	tenants := newTestTenants(t)
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected int
+	}{
+		{"no identification", nil, http.StatusUnauthorized},
+		{"invalid API key", map[string]string{"X-API-Key": "guess"}, http.StatusUnauthorized},
+		{"key for another tenant", map[string]string{"X-API-Key": "retail-key", "X-Tenant-ID": "wholesale"}, http.StatusForbidden},
+		{"header only for a keyed tenant", map[string]string{"X-Tenant-ID": "retail"}, http.StatusUnauthorized},
+		{"unknown tenant", map[string]string{"X-Tenant-ID": "outlet"}, http.StatusNotFound},
+		{"key and matching tenant", map[string]string{"X-API-Key": "retail-key", "X-Tenant-ID": "retail"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		rec := postTenantAvailability(tenants, "PROD-123", tt.headers)
+		if rec.Code != tt.expected {
+			t.Errorf("%s: expected HTTP %d, got %d: %s", tt.name, tt.expected, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTenants_CredentialsLimitedToTenant(t *testing.T) {
+	tenants := newTestTenants(t)
+	keys, _ := NewAPIKeyAuthenticator([]APIKey{
+		{Name: "wholesale-app", SHA256: hashAPIKey("wholesale-secret"), Scopes: []string{ScopeAvailabilityRead}, Tenant: "wholesale"},
+	})
+	handler := NewAuth(keys).Require(ScopeAvailabilityRead, tenants.Handler((*AvailabilityHandler).HandleCheckAvailability))
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected int
+	}{
+		{"own tenant", map[string]string{"X-Tenant-ID": "wholesale"}, http.StatusOK},
+		{"other tenant", map[string]string{"X-API-Key": "retail-key"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		body := `{"product_id": "PROD-456", "quantity": 1, "warehouse_location": "DE-Berlin"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer wholesale-secret")
+		for name, value := range tt.headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, rec.Code)
+		}
+	}
+}
+
+func TestCheckAvailability_RefusesOtherTenants(t *testing.T) {
+	service := NewAvailabilityService(NewMockInventoryAdapter(), WithTenant("retail"))
+
+	for _, tenantID := range []string{"wholesale", ""} {
+		resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: "DE-Berlin", TenantID: tenantID})
+		if resp.Available || resp.ReasonCode != ReasonTenantMismatch {
+			t.Errorf("Tenant %q: expected tenant_mismatch, got %+v", tenantID, resp)
+		}
+	}
+
+	resp := service.CheckAvailability(t.Context(), Request{ProductID: "PROD-123", Quantity: 1, WarehouseLocation: "DE-Berlin", TenantID: "retail"})
+	if !resp.Available {
+		t.Errorf("Expected own tenant to be served, got %+v", resp)
+	}
+}
+
+func TestTenantRegistry_RejectsSharedDataDir(t *testing.T) {
+	root := t.TempDir()
+	writeTenantDir(t, root, "shared", `[]`)
+	path := filepath.Join(root, "tenants.json")
+	os.WriteFile(path, []byte(`[
+		{"id": "a", "data_dir": "shared"},
+		{"id": "b", "data_dir": "shared/../shared/"}
+	]`), 0o644)
+
+	if err := NewTenantRegistry(path, DefaultConfig().Inventory).Load(); err == nil {
+		t.Error("Expected tenants sharing a data directory to be rejected")
+	}
+}
+
+func TestTenantRegistry_RejectsUnhashedAPIKeys(t *testing.T) {
+	root := t.TempDir()
+	writeTenantDir(t, root, "retail", `[]`)
+	path := filepath.Join(root, "tenants.json")
+	os.WriteFile(path, []byte(`[{"id": "retail", "data_dir": "retail", "api_keys": ["retail-key"]}]`), 0o644)
+
+	if err := NewTenantRegistry(path, DefaultConfig().Inventory).Load(); err == nil {
+		t.Error("Expected a plaintext API key to be rejected")
+	}
+}
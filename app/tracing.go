@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Span kinds, numbered as in OTLP
+const (
+	SpanKindInternal = 1
+	SpanKindServer   = 2
+	SpanKindClient   = 3
+)
+
+// TraceID and SpanID identify traces and spans as in W3C trace context
+type (
+	TraceID [16]byte
+	SpanID  [8]byte
+)
+
+// String returns the ID in lowercase hex
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// String returns the ID in lowercase hex
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// Span is one timed operation in a trace. Its fields are written while the
+// span is open and read by exporters once it has ended; changes after End
+// are ignored. A nil *Span is a valid span that records nothing, so code can
+// trace unconditionally
+type Span struct {
+	SpanContext
+	Parent     SpanID // zero for a root span
+	Name       string
+	Kind       int
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes []slog.Attr
+	Error      string // set when the operation failed
+
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.ended {
+		s.Attributes = append(s.Attributes, attrs...)
+	}
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.ended {
+		s.Error = err.Error()
+	}
+	s.mu.Unlock()
+}
+
+// End ends the span and hands it to the exporter if it is sampled. Only the
+// first call has an effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mu.Unlock()
+	if s.Sampled {
+		s.tracer.exporter.ExportSpan(s)
+	}
+}
+
+// SpanExporter receives spans as they end
+type SpanExporter interface {
+	ExportSpan(span *Span)
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer starts spans and hands them to an exporter. A nil *Tracer traces
+// nothing
+type Tracer struct {
+	exporter SpanExporter
+}
+
+// NewTracer creates a tracer exporting to exporter
+func NewTracer(exporter SpanExporter) *Tracer {
+	return &Tracer{exporter: exporter}
+}
+
+// spanKey is the context key for the current span
+type spanKey struct{}
+
+// spanFromContext returns the current span of ctx, or nil
+func spanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start begins a span in a new trace, or continuing the remote trace when
+// remote is valid, and returns a context carrying it
+func (t *Tracer) Start(ctx context.Context, name string, kind int, remote SpanContext, attrs ...slog.Attr) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{Name: name, Kind: kind, StartTime: time.Now(), Attributes: attrs, tracer: t}
+	if remote.TraceID != (TraceID{}) {
+		span.TraceID = remote.TraceID
+		span.Parent = remote.SpanID
+		span.Sampled = remote.Sampled
+	} else {
+		rand.Read(span.TraceID[:])
+		span.Sampled = true
+	}
+	rand.Read(span.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// startSpan begins a child of the current span of ctx. Without a current
+// span nothing is traced and the returned span is nil
+func startSpan(ctx context.Context, name string, kind int, attrs ...slog.Attr) (context.Context, *Span) {
+	parent := spanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		SpanContext: SpanContext{TraceID: parent.TraceID, Sampled: parent.Sampled},
+		Parent:      parent.SpanID,
+		Name:        name,
+		Kind:        kind,
+		StartTime:   time.Now(),
+		Attributes:  attrs,
+		tracer:      parent.tracer,
+	}
+	rand.Read(span.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// Middleware traces each request in a server span, continuing the caller's
+// trace from its traceparent header. The span is named after the route
+// resolved by withRoute
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	if t == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Method
+		attrs := []slog.Attr{
+			slog.String("http.request.method", r.Method),
+			slog.String("url.path", r.URL.Path),
+		}
+		if route := routeFromContext(r.Context()); route != "" {
+			name += " " + route
+			attrs = append(attrs, slog.String("http.route", route))
+		}
+		remote, _ := parseTraceparent(r.Header.Get("traceparent"))
+		ctx, span := t.Start(r.Context(), name, SpanKindServer, remote, attrs...)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttributes(slog.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.RecordError(errorStatus(recorder.status))
+		}
+	})
+}
+
+// Shutdown flushes and stops the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+// errorStatus describes a failed response for a span
+type errorStatus int
+
+func (s errorStatus) Error() string { return http.StatusText(int(s)) }
+
+// parseTraceparent reads a W3C traceparent header. ok is false if the header
+// is missing or malformed, in which case a new trace is started
+func parseTraceparent(header string) (sc SpanContext, ok bool) {
+	// version-traceid-spanid-flags; later versions may append fields
+	if len(header) < 55 || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return SpanContext{}, false
+	}
+	if len(header) > 55 && (header[:2] == "00" || header[55] != '-') {
+		return SpanContext{}, false
+	}
+	version, ok1 := decodeHex(header[0:2], 1)
+	traceID, ok2 := decodeHex(header[3:35], 16)
+	spanID, ok3 := decodeHex(header[36:52], 8)
+	flags, ok4 := decodeHex(header[53:55], 1)
+	if !ok1 || !ok2 || !ok3 || !ok4 || version[0] == 0xff {
+		return SpanContext{}, false
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	if sc.TraceID == (TraceID{}) || sc.SpanID == (SpanID{}) {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// decodeHex decodes n bytes of lowercase hex
+func decodeHex(s string, n int) ([]byte, bool) {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil && len(b) == n
+}
+
+// injectTraceContext sets the traceparent header for an outbound call made
+// within the current span of ctx
+func injectTraceContext(ctx context.Context, header http.Header) {
+	span := spanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	flags := "00"
+	if span.Sampled {
+		flags = "01"
+	}
+	header.Set("traceparent", "00-"+span.TraceID.String()+"-"+span.SpanID.String()+"-"+flags)
+}
+
+// InMemoryExporter keeps ended spans in memory, for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// ExportSpan records the span
+func (e *InMemoryExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	e.spans = append(e.spans, span)
+	e.mu.Unlock()
+}
+
+// Spans returns the spans recorded so far, in the order they ended
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]*Span(nil), e.spans...)
+}
+
+// Shutdown does nothing; the spans stay readable
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
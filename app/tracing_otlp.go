@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OTLP export limits: spans beyond maxQueue wait for the next flush are
+// dropped, and each request carries at most batchSize spans
+const (
+	otlpMaxQueue    = 2048
+	otlpBatchSize   = 512
+	otlpSendTimeout = 10 * time.Second
+)
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP over
+// HTTP with JSON encoding. Spans are queued as they end and sent in batches
+// every interval, so tracing never waits on the collector
+type OTLPExporter struct {
+	endpoint    string // full URL, such as http://collector:4318/v1/traces
+	serviceName string
+	client      *http.Client
+
+	mu      sync.Mutex
+	queue   []*Span
+	dropped int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewOTLPExporter creates an exporter sending to endpoint every interval
+func NewOTLPExporter(endpoint, serviceName string, interval time.Duration) *OTLPExporter {
+	e := &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: otlpSendTimeout},
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.run(interval)
+	return e
+}
+
+// ExportSpan queues the span for the next batch
+func (e *OTLPExporter) ExportSpan(span *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= otlpMaxQueue {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, span)
+}
+
+// run flushes the queue every interval until Shutdown
+func (e *OTLPExporter) run(interval time.Duration) {
+	defer close(e.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+			if err := e.Flush(context.Background()); err != nil {
+				slog.Warn("failed to export spans", "endpoint", e.endpoint, "error", err)
+			}
+		}
+	}
+}
+
+// Flush sends every queued span. Spans in a batch the collector did not
+// accept are dropped rather than retried
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	spans, dropped := e.queue, e.dropped
+	e.queue, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		slog.Warn("dropped spans, export queue full", "spans", dropped)
+	}
+	for len(spans) > 0 {
+		n := min(len(spans), otlpBatchSize)
+		if err := e.send(ctx, spans[:n]); err != nil {
+			return fmt.Errorf("failed to send %d spans: %w", len(spans), err)
+		}
+		spans = spans[n:]
+	}
+	return nil
+}
+
+// Shutdown stops the background flushes and sends what is still queued.
+// Calling it again only flushes
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	<-e.done
+	return e.Flush(ctx)
+}
+
+// send posts one batch to the collector
+func (e *OTLPExporter) send(ctx context.Context, spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+// OTLP JSON request, trimmed to the fields the service sets. IDs are hex
+// and 64-bit integers are strings, as the OTLP JSON mapping requires
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            *otlpStatus    `json:"status,omitempty"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code"` // 2 is error
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string         `json:"stringValue,omitempty"`
+		BoolValue   *bool           `json:"boolValue,omitempty"`
+		IntValue    *string         `json:"intValue,omitempty"`
+		DoubleValue *float64        `json:"doubleValue,omitempty"`
+		ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	}
+	otlpArrayValue struct {
+		Values []otlpValue `json:"values"`
+	}
+)
+
+// encode converts spans into an OTLP export request
+func (e *OTLPExporter) encode(spans []*Span) otlpRequest {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: "golang-assessment"}}
+	for _, span := range spans {
+		encoded := otlpSpan{
+			TraceID:           span.TraceID.String(),
+			SpanID:            span.SpanID.String(),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if span.Parent != (SpanID{}) {
+			encoded.ParentSpanID = span.Parent.String()
+		}
+		if span.Error != "" {
+			encoded.Status = &otlpStatus{Code: 2, Message: span.Error}
+		}
+		scope.Spans = append(scope.Spans, encoded)
+	}
+
+	resource := otlpResource{Attributes: otlpAttributes([]slog.Attr{slog.String("service.name", e.serviceName)})}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{Resource: resource, ScopeSpans: []otlpScopeSpans{scope}}}}
+}
+
+// otlpAttributes converts span attributes into OTLP key-values
+func otlpAttributes(attrs []slog.Attr) []otlpKeyValue {
+	var out []otlpKeyValue
+	for _, attr := range attrs {
+		out = append(out, otlpKeyValue{Key: attr.Key, Value: otlpAttributeValue(attr.Value.Resolve())})
+	}
+	return out
+}
+
+// otlpAttributeValue converts a value into its OTLP type. Lists of strings
+// become arrays; other values without an OTLP type are written as strings
+func otlpAttributeValue(v slog.Value) otlpValue {
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpValue{BoolValue: &b}
+	case slog.KindInt64:
+		i := strconv.FormatInt(v.Int64(), 10)
+		return otlpValue{IntValue: &i}
+	case slog.KindUint64:
+		i := strconv.FormatUint(v.Uint64(), 10)
+		return otlpValue{IntValue: &i}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return otlpValue{DoubleValue: &f}
+	}
+	if list, ok := v.Any().([]string); ok {
+		array := &otlpArrayValue{Values: []otlpValue{}}
+		for _, s := range list {
+			array.Values = append(array.Values, otlpValue{StringValue: &s})
+		}
+		return otlpValue{ArrayValue: array}
+	}
+	s := v.String()
+	return otlpValue{StringValue: &s}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter_SendsSpansOnShutdown(t *testing.T) {
+	requests := make(chan map[string]any, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected export request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		var decoded map[string]any
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+		}
+		requests <- decoded
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "availability-test", time.Hour)
+	ctx, root := NewTracer(exporter).Start(t.Context(), "POST /api/check-availability", SpanKindServer, SpanContext{})
+	_, child := startSpan(ctx, "availability.check", SpanKindInternal,
+		slog.String("product_id", "PROD-123"),
+		slog.Int("quantity", 5),
+		slog.Bool("weekend", false),
+		slog.Any("rules", []string{"reserve_buffer"}))
+	child.RecordError(errors.New("inventory source unavailable"))
+	child.End()
+	root.End()
+
+	if err := exporter.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	var got map[string]any
+	select {
+	case got = <-requests:
+	default:
+		t.Fatal("Expected the queued spans to be sent on shutdown")
+	}
+	encoded, _ := json.Marshal(got)
+	resourceSpans := got["resourceSpans"].([]any)[0].(map[string]any)
+	service := resourceSpans["resource"].(map[string]any)["attributes"].([]any)[0].(map[string]any)
+	if service["key"] != "service.name" || service["value"].(map[string]any)["stringValue"] != "availability-test" {
+		t.Errorf("Expected the service name as a resource attribute, got %v", service)
+	}
+
+	spans := resourceSpans["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d in %s", len(spans), encoded)
+	}
+	span := spans[0].(map[string]any)
+	want := map[string]any{
+		"name":         "availability.check",
+		"traceId":      root.TraceID.String(),
+		"spanId":       child.SpanID.String(),
+		"parentSpanId": root.SpanID.String(),
+		"kind":         float64(SpanKindInternal),
+	}
+	for key, value := range want {
+		if span[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, span[key])
+		}
+	}
+	if status := span["status"].(map[string]any); status["code"] != float64(2) || status["message"] != "inventory source unavailable" {
+		t.Errorf("Expected an error status, got %v", status)
+	}
+
+	attrs, _ := json.Marshal(span["attributes"])
+	wantAttrs := `[{"key":"product_id","value":{"stringValue":"PROD-123"}},{"key":"quantity","value":{"intValue":"5"}},{"key":"weekend","value":{"boolValue":false}},{"key":"rules","value":{"arrayValue":{"values":[{"stringValue":"reserve_buffer"}]}}}]`
+	if string(attrs) != wantAttrs {
+		t.Errorf("Unexpected attributes:\n%s\nwant:\n%s", attrs, wantAttrs)
+	}
+	if _, ok := spans[1].(map[string]any)["parentSpanId"]; ok {
+		t.Error("Expected no parent on the root span")
+	}
+}
+
+func TestOTLPExporter_ReportsCollectorErrors(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, "availability-test", time.Hour)
+	_, span := NewTracer(exporter).Start(t.Context(), "test", SpanKindInternal, SpanContext{})
+	span.End()
+
+	if err := exporter.Shutdown(t.Context()); err == nil {
+		t.Error("Expected an error when the collector rejects the spans")
+	}
+}
+
+func TestOTLPExporter_ShutdownTwice(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "availability-test", time.Hour)
+	if err := exporter.Shutdown(t.Context()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	if err := exporter.Shutdown(t.Context()); err != nil {
+		t.Errorf("Expected a second shutdown to be harmless, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// findSpan returns the exported span with the given name
+func findSpan(t *testing.T, spans []*Span, name string) *Span {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	t.Fatalf("Expected a span named %q, got %v", name, names)
+	return nil
+}
+
+// spanAttr returns a span attribute's value, or the zero value if it is missing
+func spanAttr(span *Span, key string) slog.Value {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return slog.Value{}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantOK      bool
+		wantSampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"later version with extra fields", "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"missing", "", false, false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace ID", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span ID", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"invalid version", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"version 00 with extra fields", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"truncated", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := parseTraceparent(tt.header)
+			if ok != tt.wantOK {
+				t.Fatalf("Expected ok=%v, got %v", tt.wantOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("Unexpected IDs %s %s", sc.TraceID, sc.SpanID)
+			}
+			if sc.Sampled != tt.wantSampled {
+				t.Errorf("Expected sampled=%v, got %v", tt.wantSampled, sc.Sampled)
+			}
+		})
+	}
+}
+
+func TestTracer_TracesCheckAvailability(t *testing.T) {
+	exporter := &InMemoryExporter{}
+	tracer := NewTracer(exporter)
+	metrics := NewMetrics()
+	mux := http.NewServeMux()
+	handler := NewAvailabilityHandler(NewAvailabilityService(NewMockInventoryAdapter()), nil)
+	mux.HandleFunc("/api/check-availability", handler.HandleCheckAvailability)
+
+	body := `{"product_id": "PROD-123", "quantity": 5, "warehouse_location": "DE-Berlin"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	withRoute(mux, metrics.Instrument(tracer.Middleware(mux))).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.Spans()
+	server := findSpan(t, spans, "POST /api/check-availability")
+	check := findSpan(t, spans, "availability.check")
+	lookup := findSpan(t, spans, "inventory.lookup")
+
+	if server.Kind != SpanKindServer || server.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.Parent.String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the server span to continue the caller's trace, got %+v", server.SpanContext)
+	}
+	if check.Parent != server.SpanID || lookup.Parent != check.SpanID {
+		t.Error("Expected server > availability.check > inventory.lookup")
+	}
+	if lookup.TraceID != server.TraceID {
+		t.Error("Expected every span in the caller's trace")
+	}
+
+	wantAttrs := map[string]string{
+		"product_id":  "PROD-123",
+		"warehouse":   "DE-Berlin",
+		"outcome":     StatusAvailable,
+		"reason_code": ReasonSufficientStock,
+	}
+	for key, want := range wantAttrs {
+		if got := spanAttr(check, key).String(); got != want {
+			t.Errorf("Expected %s=%s, got %s", key, want, got)
+		}
+	}
+	if rules, _ := spanAttr(check, "rules").Any().([]string); !slices.Contains(rules, "reserve_buffer") {
+		t.Errorf("Expected the reserve buffer among the rules applied, got %v", rules)
+	}
+	if got := spanAttr(lookup, "stock_level").Int64(); got != 100 {
+		t.Errorf("Expected stock_level=100 on the lookup span, got %d", got)
+	}
+
+	// The route matched inside the traced handler still labels the metrics
+	assertMetric(t, scrapeMetrics(t, metrics), `availability_http_requests_total{route="/api/check-availability",status="200"} 1`)
+}
+
+func TestTracer_FailedLookupMarksSpan(t *testing.T) {
+	tests := []struct {
+		name       string
+		adapter    InventoryAdapter
+		productID  string
+		wantFailed bool
+	}{
+		{"source down", &UnavailableInventoryAdapter{}, "PROD-123", true},
+		{"product not found is an answer", NewMockInventoryAdapter(), "PROD-999", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &InMemoryExporter{}
+			ctx, root := NewTracer(exporter).Start(t.Context(), "test", SpanKindInternal, SpanContext{})
+			NewAvailabilityService(tt.adapter).CheckAvailability(ctx, Request{ProductID: tt.productID, Quantity: 1, WarehouseLocation: "DE-Berlin"})
+			root.End()
+
+			lookup := findSpan(t, exporter.Spans(), "inventory.lookup")
+			if failed := lookup.Error != ""; failed != tt.wantFailed {
+				t.Errorf("Expected failed=%v, got error %q", tt.wantFailed, lookup.Error)
+			}
+		})
+	}
+}
+
+func TestTracer_UnsampledTracesAreNotExported(t *testing.T) {
+	exporter := &InMemoryExporter{}
+	remote, _ := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	ctx, root := NewTracer(exporter).Start(t.Context(), "test", SpanKindServer, remote)
+	_, child := startSpan(ctx, "child", SpanKindInternal)
+	child.End()
+	root.End()
+
+	if spans := exporter.Spans(); len(spans) != 0 {
+		t.Errorf("Expected no spans exported, got %d", len(spans))
+	}
+}
+
+func TestTracer_DisabledTracesNothing(t *testing.T) {
+	var tracer *Tracer
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if spanFromContext(r.Context()) != nil {
+			t.Error("Expected no span without a tracer")
+		}
+	})
+	tracer.Middleware(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// A nil span accepts every call
+	_, span := startSpan(t.Context(), "untraced", SpanKindInternal)
+	span.SetAttributes(slog.String("key", "value"))
+	span.RecordError(ErrTimeout)
+	span.End()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Warehouse describes a warehouse known to the service
+type Warehouse struct {
+	Code           string   `json:"code"`
+	Name           string   `json:"name"`
+	Country        string   `json:"country"`
+	TimeZone       string   `json:"time_zone"`
+	Active         bool     `json:"active"`
+	ShippingCutoff string   `json:"shipping_cutoff"`    // local time of day, "15:04"
+	Holidays       []string `json:"holidays,omitempty"` // dates without shipping, "2006-01-02"
+
+	location  *time.Location
+	hasCutoff bool          // a "00:00" cut-off is still a cut-off
+	cutoff    time.Duration // ShippingCutoff as time since local midnight
+	holidays  map[string]bool
+}
+
+// WarehouseRegistry holds the warehouses the service accepts, loaded from a JSON file
+type WarehouseRegistry struct {
+	filePath   string
+	warehouses []Warehouse
+	byCode     map[string]Warehouse
+}
+
+// NewWarehouseRegistry creates a registry backed by the given JSON file
+func NewWarehouseRegistry(filePath string) *WarehouseRegistry {
+	return &WarehouseRegistry{
+		filePath: filePath,
+		byCode:   map[string]Warehouse{},
+	}
+}
+
+// Load reads and validates the warehouse definitions
+func (r *WarehouseRegistry) Load() error {
+	data, err := os.ReadFile(r.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read warehouse file: %w", err)
+	}
+
+	var warehouses []Warehouse
+	err = json.Unmarshal(data, &warehouses)
+	if err != nil {
+		return fmt.Errorf("failed to parse warehouse JSON: %w", err)
+	}
+
+	return r.setWarehouses(warehouses)
+}
+
+// setWarehouses validates warehouses and replaces the registry with them
+func (r *WarehouseRegistry) setWarehouses(warehouses []Warehouse) error {
+	byCode := make(map[string]Warehouse, len(warehouses))
+	for i := range warehouses {
+		w := &warehouses[i]
+		if w.Code == "" {
+			return fmt.Errorf("warehouse without code in %s", r.filePath)
+		}
+		if _, ok := byCode[w.Code]; ok {
+			return fmt.Errorf("duplicate warehouse code %s", w.Code)
+		}
+		location, err := time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return fmt.Errorf("warehouse %s: invalid time zone %q: %w", w.Code, w.TimeZone, err)
+		}
+		w.location = location
+		if w.ShippingCutoff != "" {
+			cutoff, err := time.Parse("15:04", w.ShippingCutoff)
+			if err != nil {
+				return fmt.Errorf("warehouse %s: invalid shipping cut-off %q (want HH:MM)", w.Code, w.ShippingCutoff)
+			}
+			w.hasCutoff = true
+			w.cutoff = time.Duration(cutoff.Hour())*time.Hour + time.Duration(cutoff.Minute())*time.Minute
+		}
+		w.holidays = make(map[string]bool, len(w.Holidays))
+		for _, holiday := range w.Holidays {
+			if _, err := time.Parse(time.DateOnly, holiday); err != nil {
+				return fmt.Errorf("warehouse %s: invalid holiday %q (want YYYY-MM-DD)", w.Code, holiday)
+			}
+			w.holidays[holiday] = true
+		}
+		byCode[w.Code] = *w
+	}
+
+	sort.Slice(warehouses, func(i, j int) bool { return warehouses[i].Code < warehouses[j].Code })
+	r.warehouses = warehouses
+	r.byCode = byCode
+	return nil
+}
+
+// List returns every warehouse ordered by code
+func (r *WarehouseRegistry) List() []Warehouse {
+	return append([]Warehouse(nil), r.warehouses...)
+}
+
+// Lookup returns the warehouse with the given code
+func (r *WarehouseRegistry) Lookup(code string) (Warehouse, bool) {
+	w, ok := r.byCode[code]
+	return w, ok
+}
+
+// nextShippingDay returns the first date on or after day on which the
+// warehouse ships: not a weekend, not a holiday and, for the warehouse's
+// current local date, not past the shipping cut-off. Without a warehouse
+// only weekends are skipped
+func nextShippingDay(day time.Time, w *Warehouse) time.Time {
+	if w != nil && w.hasCutoff {
+		local := now().In(w.location)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.location)
+		if day.Format(time.DateOnly) == local.Format(time.DateOnly) && local.Sub(midnight) >= w.cutoff {
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+
+	for {
+		weekday := day.Weekday()
+		weekend := weekday == time.Saturday || weekday == time.Sunday
+		holiday := w != nil && w.holidays[day.Format(time.DateOnly)]
+		if !weekend && !holiday {
+			return day
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+}
+
+// Suggest returns the known warehouse code closest to an unknown one, or ""
+// if nothing is close enough to be a plausible typo
+func (r *WarehouseRegistry) Suggest(code string) string {
+	best := ""
+	bestDistance := len(code)/3 + 2 // tolerate roughly one typo per three characters
+	for _, w := range r.warehouses {
+		if strings.EqualFold(w.Code, code) {
+			return w.Code
+		}
+		distance := editDistance(strings.ToLower(code), strings.ToLower(w.Code))
+		if distance < bestDistance {
+			best = w.Code
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
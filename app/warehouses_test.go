@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadTestWarehouses loads the shipped warehouses.json
+func loadTestWarehouses(t *testing.T) *WarehouseRegistry {
+	t.Helper()
+	registry := NewWarehouseRegistry("warehouses.json")
+	if err := registry.Load(); err != nil {
+		t.Fatalf("Failed to load warehouses: %v", err)
+	}
+	return registry
+}
+
+func TestWarehouseRegistry_Lookup(t *testing.T) {
+	registry := loadTestWarehouses(t)
+
+	w, ok := registry.Lookup("DE-Berlin")
+	if !ok {
+		t.Fatal("Expected DE-Berlin to be registered")
+	}
+	if w.TimeZone != "Europe/Berlin" || w.Country != "DE" || !w.Active {
+		t.Errorf("Unexpected warehouse: %+v", w)
+	}
+	if _, ok := registry.Lookup("DE-berlin"); ok {
+		t.Error("Expected lookup to be case-sensitive")
+	}
+}
+
+func TestWarehouseRegistry_Suggest(t *testing.T) {
+	registry := loadTestWarehouses(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"DE-berlin", "DE-Berlin"},
+		{"de-berlin", "DE-Berlin"},
+		{"US-NewYrok", "US-NewYork"},
+		{"UK-Londn", "UK-London"},
+		{"FR-Paris", ""},
+	}
+	for _, tt := range tests {
+		if got := registry.Suggest(tt.input); got != tt.expected {
+			t.Errorf("Suggest(%q): expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestWarehouseRegistry_RejectsInvalidTimeZone(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warehouses.json")
+	os.WriteFile(path, []byte(`[{"code": "XX-Nowhere", "time_zone": "Mars/Olympus", "active": true}]`), 0o644)
+
+	if err := NewWarehouseRegistry(path).Load(); err == nil {
+		t.Error("Expected invalid time zone to be rejected")
+	}
+}
+
+func TestHandleCheckAvailability_UnknownWarehouseSuggestion(t *testing.T) {
+	handler := NewAvailabilityHandler(NewAvailabilityService(NewMockInventoryAdapter()), loadTestWarehouses(t))
+
+	body := `{"product_id": "PROD-123", "quantity": 1, "warehouse_location": "DE-berlin"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/check-availability", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.HandleCheckAvailability(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `did you mean "DE-Berlin"?`) {
+		t.Errorf("Expected suggestion in body, got %q", rec.Body.String())
+	}
+}
+
+func TestHandleListWarehouses(t *testing.T) {
+	handler := NewAvailabilityHandler(NewAvailabilityService(NewMockInventoryAdapter()), loadTestWarehouses(t))
+
+	rec := httptest.NewRecorder()
+	handler.HandleListWarehouses(rec, httptest.NewRequest(http.MethodGet, "/api/warehouses", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Warehouses []Warehouse `json:"warehouses"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(body.Warehouses) != 3 || body.Warehouses[0].Code != "DE-Berlin" {
+		t.Errorf("Unexpected warehouses: %+v", body.Warehouses)
+	}
+}